@@ -0,0 +1,510 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultBreakerThreshold is how many consecutive failures open the circuit
+	defaultBreakerThreshold = 5
+
+	// defaultBreakerTimeout is how long the circuit stays open between probes
+	defaultBreakerTimeout = 5 * time.Second
+
+	// defaultProbeTimeout bounds each probe of an open circuit
+	defaultProbeTimeout = time.Second
+
+	// probeKey is read to probe stores that cannot be pinged
+	probeKey = "__breaker:probe"
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState string
+
+const (
+	// CircuitClosed passes calls to the store
+	CircuitClosed CircuitState = "closed"
+
+	// CircuitOpen fails calls fast, or serves them from the fallback store
+	CircuitOpen CircuitState = "open"
+
+	// CircuitHalfOpen is the state while a probe checks whether the store
+	// recovered. Calls are still handled as if the circuit were open.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreakerOptions configures a CircuitBreakerStore
+type CircuitBreakerOptions struct {
+	// FailureThreshold is how many consecutive failures open the circuit
+	// Default: 5
+	FailureThreshold int
+
+	// OpenTimeout is how long the circuit stays open before the store is
+	// probed, and between probes while it keeps failing
+	// Default: 5 seconds
+	OpenTimeout time.Duration
+
+	// ProbeTimeout bounds each probe
+	// Default: 1 second
+	ProbeTimeout time.Duration
+
+	// Fallback serves calls while the circuit is not closed instead of
+	// failing them with ErrRedisUnavailable, typically a bounded MemoryStore.
+	// It is cleared whenever the circuit opens, and writes made to it are not
+	// replayed to the store. Deletes, tag invalidations and clears are applied
+	// to it but still fail with ErrRedisUnavailable, since the store keeps the
+	// removed keys. The fallback is closed together with the store.
+	// Default: nil
+	Fallback Store
+
+	// OnStateChange is called after every state transition
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreakerStore stops calling a store, typically a RedisStore, after
+// consecutive failures so that an outage fails fast instead of waiting on
+// timeouts. While the circuit is open the store is probed in the background
+// until it answers again. Locks always fail with ErrRedisUnavailable while
+// the circuit is open, since a local lock would not exclude other instances.
+type CircuitBreakerStore struct {
+	next Store
+	opts CircuitBreakerOptions
+
+	open     atomic.Bool
+	failures atomic.Int64
+
+	// Store counters last read while the circuit was closed
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+
+	mu        sync.Mutex
+	state     CircuitState
+	closing   chan struct{}
+	closeOnce sync.Once
+	probing   sync.WaitGroup
+}
+
+// NewCircuitBreakerStore wraps next with a circuit breaker
+func NewCircuitBreakerStore(next Store, opts CircuitBreakerOptions) *CircuitBreakerStore {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = defaultBreakerThreshold
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = defaultBreakerTimeout
+	}
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = defaultProbeTimeout
+	}
+
+	return &CircuitBreakerStore{
+		next:    next,
+		opts:    opts,
+		state:   CircuitClosed,
+		closing: make(chan struct{}),
+	}
+}
+
+// State returns the current state of the circuit
+func (s *CircuitBreakerStore) State() CircuitState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// isFailure reports whether err means the store could not serve the call,
+// as opposed to a missing key, a rejected command or a canceled call
+func isFailure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrNotFound),
+		errors.Is(err, ErrFlushDisabled),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+
+	// Redis answered, which only counts if it cannot serve data right now
+	var reply redis.Error
+	if errors.As(err, &reply) {
+		msg := reply.Error()
+		return strings.HasPrefix(msg, "LOADING") ||
+			strings.HasPrefix(msg, "MASTERDOWN") ||
+			strings.HasPrefix(msg, "CLUSTERDOWN")
+	}
+
+	// Values that cannot be serialized never reached the store
+	var unsupportedType *json.UnsupportedTypeError
+	var unsupportedValue *json.UnsupportedValueError
+	var marshaler *json.MarshalerError
+	return !errors.As(err, &unsupportedType) &&
+		!errors.As(err, &unsupportedValue) &&
+		!errors.As(err, &marshaler)
+}
+
+// record counts the outcome of a call, opening the circuit once failures
+// reach the threshold
+func (s *CircuitBreakerStore) record(err error) {
+	if !isFailure(err) {
+		if s.failures.Load() != 0 {
+			s.failures.Store(0)
+		}
+		return
+	}
+
+	if s.failures.Add(1) >= int64(s.opts.FailureThreshold) {
+		s.trip()
+	}
+}
+
+// trip opens a closed circuit and starts probing the store
+func (s *CircuitBreakerStore) trip() {
+	s.mu.Lock()
+	if s.state != CircuitClosed {
+		s.mu.Unlock()
+		return
+	}
+	select {
+	case <-s.closing:
+		s.mu.Unlock()
+		return
+	default:
+	}
+
+	// Drop what the fallback kept from a previous outage
+	if s.opts.Fallback != nil {
+		s.opts.Fallback.Clear(context.Background())
+	}
+
+	s.state = CircuitOpen
+	s.open.Store(true)
+	s.probing.Add(1)
+	s.mu.Unlock()
+
+	s.notify(CircuitClosed, CircuitOpen)
+	go s.probe()
+}
+
+// transition moves the circuit to state and reports the change
+func (s *CircuitBreakerStore) transition(to CircuitState) {
+	s.mu.Lock()
+	from := s.state
+	s.state = to
+	if to == CircuitClosed {
+		s.failures.Store(0)
+		s.open.Store(false)
+	}
+	s.mu.Unlock()
+
+	if from != to {
+		s.notify(from, to)
+	}
+}
+
+// notify calls the state change callback
+func (s *CircuitBreakerStore) notify(from, to CircuitState) {
+	if s.opts.OnStateChange != nil {
+		s.opts.OnStateChange(from, to)
+	}
+}
+
+// probe checks the store every OpenTimeout until it answers, then closes the circuit
+func (s *CircuitBreakerStore) probe() {
+	defer s.probing.Done()
+
+	for {
+		select {
+		case <-time.After(s.opts.OpenTimeout):
+		case <-s.closing:
+			return
+		}
+
+		s.transition(CircuitHalfOpen)
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.opts.ProbeTimeout)
+		err := s.ping(ctx)
+		cancel()
+
+		if err == nil {
+			s.transition(CircuitClosed)
+			return
+		}
+		s.transition(CircuitOpen)
+	}
+}
+
+// ping checks whether the store answers, preferring its Ping method
+func (s *CircuitBreakerStore) ping(ctx context.Context) error {
+	if pinger, ok := s.next.(interface{ Ping(context.Context) error }); ok {
+		return pinger.Ping(ctx)
+	}
+
+	_, err := s.next.Get(ctx, probeKey)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// call runs fn against the store while the circuit is closed, and against
+// the fallback otherwise
+func call[T any](s *CircuitBreakerStore, fn func(store Store) (T, error)) (T, error) {
+	if s.open.Load() {
+		if s.opts.Fallback == nil {
+			var zero T
+			return zero, ErrRedisUnavailable
+		}
+		return fn(s.opts.Fallback)
+	}
+
+	result, err := fn(s.next)
+	s.record(err)
+	return result, err
+}
+
+// do is call for operations without a result
+func (s *CircuitBreakerStore) do(fn func(store Store) error) error {
+	_, err := call(s, func(store Store) (struct{}, error) {
+		return struct{}{}, fn(store)
+	})
+	return err
+}
+
+// invalidate is call for operations removing keys. While the circuit is
+// open they are applied to the fallback, so local reads stop returning the
+// keys, but fail with ErrRedisUnavailable since the store still has them.
+func invalidate[T any](s *CircuitBreakerStore, fn func(store Store) (T, error)) (T, error) {
+	if !s.open.Load() {
+		result, err := fn(s.next)
+		s.record(err)
+		return result, err
+	}
+
+	var result T
+	if s.opts.Fallback != nil {
+		result, _ = fn(s.opts.Fallback)
+	}
+	return result, ErrRedisUnavailable
+}
+
+// Get retrieves a value
+func (s *CircuitBreakerStore) Get(ctx context.Context, key string) (interface{}, error) {
+	return call(s, func(store Store) (interface{}, error) {
+		return store.Get(ctx, key)
+	})
+}
+
+// GetWithTTL retrieves a value and its remaining TTL, 0 if it does not expire
+func (s *CircuitBreakerStore) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) {
+	var ttl time.Duration
+	value, err := call(s, func(store Store) (interface{}, error) {
+		ttlGetter, ok := store.(TTLGetter)
+		if !ok {
+			return store.Get(ctx, key)
+		}
+		value, remaining, err := ttlGetter.GetWithTTL(ctx, key)
+		ttl = remaining
+		return value, err
+	})
+	return value, ttl, err
+}
+
+// Set stores a value
+func (s *CircuitBreakerStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return s.do(func(store Store) error {
+		return store.Set(ctx, key, value, ttl)
+	})
+}
+
+// SetWithTags stores a value and associates the key with the given tags
+func (s *CircuitBreakerStore) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	return s.do(func(store Store) error {
+		tagged, ok := store.(TagStore)
+		if !ok {
+			return ErrTagsNotSupported
+		}
+		return tagged.SetWithTags(ctx, key, value, ttl, tags)
+	})
+}
+
+// InvalidateTags removes all keys associated with any of the tags
+func (s *CircuitBreakerStore) InvalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	return invalidate(s, func(store Store) ([]string, error) {
+		tagged, ok := store.(TagStore)
+		if !ok {
+			return nil, ErrTagsNotSupported
+		}
+		return tagged.InvalidateTags(ctx, tags...)
+	})
+}
+
+// Delete removes a value
+func (s *CircuitBreakerStore) Delete(ctx context.Context, key string) error {
+	_, err := invalidate(s, func(store Store) (struct{}, error) {
+		return struct{}{}, store.Delete(ctx, key)
+	})
+	return err
+}
+
+// DeletePrefix removes all keys starting with prefix
+func (s *CircuitBreakerStore) DeletePrefix(ctx context.Context, prefix string) error {
+	_, err := invalidate(s, func(store Store) (struct{}, error) {
+		deleter, ok := store.(PrefixDeleter)
+		if !ok {
+			return struct{}{}, ErrPrefixNotSupported
+		}
+		return struct{}{}, deleter.DeletePrefix(ctx, prefix)
+	})
+	return err
+}
+
+// Has checks if a key exists. Failures are not counted, since Has cannot report them.
+func (s *CircuitBreakerStore) Has(ctx context.Context, key string) bool {
+	if !s.open.Load() {
+		return s.next.Has(ctx, key)
+	}
+	return s.opts.Fallback != nil && s.opts.Fallback.Has(ctx, key)
+}
+
+// Increment increments a numeric value
+func (s *CircuitBreakerStore) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	return call(s, func(store Store) (int64, error) {
+		return store.Increment(ctx, key, delta)
+	})
+}
+
+// Decrement decrements a numeric value
+func (s *CircuitBreakerStore) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	return call(s, func(store Store) (int64, error) {
+		return store.Decrement(ctx, key, delta)
+	})
+}
+
+// Clear removes all entries
+func (s *CircuitBreakerStore) Clear(ctx context.Context) error {
+	_, err := invalidate(s, func(store Store) (struct{}, error) {
+		return struct{}{}, store.Clear(ctx)
+	})
+	return err
+}
+
+// Close stops probing and closes the store and the fallback
+func (s *CircuitBreakerStore) Close() error {
+	s.mu.Lock()
+	s.closeOnce.Do(func() { close(s.closing) })
+	s.mu.Unlock()
+	s.probing.Wait()
+
+	if s.opts.Fallback != nil {
+		s.opts.Fallback.Close()
+	}
+	return s.next.Close()
+}
+
+// lockStore returns the store for lock calls, which never use the fallback
+func (s *CircuitBreakerStore) lockStore() (LockStore, error) {
+	if s.open.Load() {
+		return nil, ErrRedisUnavailable
+	}
+	locks, ok := s.next.(LockStore)
+	if !ok {
+		return nil, ErrLocksNotSupported
+	}
+	return locks, nil
+}
+
+// AcquireLock takes the lock on key for ttl if it is free
+func (s *CircuitBreakerStore) AcquireLock(ctx context.Context, key, owner string, ttl time.Duration) (int64, bool, error) {
+	locks, err := s.lockStore()
+	if err != nil {
+		return 0, false, err
+	}
+	token, ok, err := locks.AcquireLock(ctx, key, owner, ttl)
+	s.record(err)
+	return token, ok, err
+}
+
+// ExtendLock resets the lease to ttl
+func (s *CircuitBreakerStore) ExtendLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	locks, err := s.lockStore()
+	if err != nil {
+		return false, err
+	}
+	held, err := locks.ExtendLock(ctx, key, owner, ttl)
+	s.record(err)
+	return held, err
+}
+
+// ReleaseLock releases the lock
+func (s *CircuitBreakerStore) ReleaseLock(ctx context.Context, key, owner string) (bool, error) {
+	locks, err := s.lockStore()
+	if err != nil {
+		return false, err
+	}
+	held, err := locks.ReleaseLock(ctx, key, owner)
+	s.record(err)
+	return held, err
+}
+
+// acquireLease takes a stampede lease, failing fast while the circuit is open
+func (s *CircuitBreakerStore) acquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	leases, ok := s.next.(leaseStore)
+	if !ok || s.open.Load() {
+		return false, ErrRedisUnavailable
+	}
+	held, err := leases.acquireLease(ctx, key, owner, ttl)
+	s.record(err)
+	return held, err
+}
+
+// releaseLease releases a stampede lease
+func (s *CircuitBreakerStore) releaseLease(ctx context.Context, key, owner string) error {
+	leases, ok := s.next.(leaseStore)
+	if !ok || s.open.Load() {
+		return ErrRedisUnavailable
+	}
+	err := leases.releaseLease(ctx, key, owner)
+	s.record(err)
+	return err
+}
+
+// AllowN records n requests against key if limit allows all of them.
+// With a fallback, limits are applied per instance while the circuit is open.
+func (s *CircuitBreakerStore) AllowN(ctx context.Context, key string, limit RateLimit, n int64) (RateLimitResult, error) {
+	return call(s, func(store Store) (RateLimitResult, error) {
+		limiter, ok := store.(RateLimitStore)
+		if !ok {
+			return RateLimitResult{}, ErrRateLimitNotSupported
+		}
+		return limiter.AllowN(ctx, key, limit, n)
+	})
+}
+
+// Evictions returns the evictions counted by the store, or the last value
+// read before the circuit opened
+func (s *CircuitBreakerStore) Evictions() uint64 {
+	if counters, ok := s.next.(storeCounters); ok && !s.open.Load() {
+		s.evictions.Store(counters.Evictions())
+	}
+	return s.evictions.Load()
+}
+
+// Expirations returns the expirations counted by the store, or the last
+// value read before the circuit opened
+func (s *CircuitBreakerStore) Expirations() uint64 {
+	if counters, ok := s.next.(storeCounters); ok && !s.open.Load() {
+		s.expirations.Store(counters.Expirations())
+	}
+	return s.expirations.Load()
+}
+
+// Unwrap returns the wrapped store
+func (s *CircuitBreakerStore) Unwrap() Store {
+	return s.next
+}
@@ -0,0 +1,220 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+// flakyStore fails every call with errDown while down is set
+type flakyStore struct {
+	*cache.MemoryStore
+	down  atomic.Bool
+	calls atomic.Int64
+}
+
+var errDown = errors.New("connection refused")
+
+func (s *flakyStore) Get(ctx context.Context, key string) (interface{}, error) {
+	s.calls.Add(1)
+	if s.down.Load() {
+		return nil, errDown
+	}
+	return s.MemoryStore.Get(ctx, key)
+}
+
+func (s *flakyStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	s.calls.Add(1)
+	if s.down.Load() {
+		return errDown
+	}
+	return s.MemoryStore.Set(ctx, key, value, ttl)
+}
+
+func (s *flakyStore) Ping(ctx context.Context) error {
+	if s.down.Load() {
+		return errDown
+	}
+	return nil
+}
+
+// transitions records circuit state changes
+type transitions struct {
+	mu     sync.Mutex
+	states []cache.CircuitState
+}
+
+func (r *transitions) record(from, to cache.CircuitState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, to)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	flaky := &flakyStore{MemoryStore: cache.NewMemoryStore(time.Minute)}
+	var changes transitions
+	store := cache.NewCircuitBreakerStore(flaky, cache.CircuitBreakerOptions{
+		FailureThreshold: 3,
+		OpenTimeout:      20 * time.Millisecond,
+		OnStateChange:    changes.record,
+	})
+	defer store.Close()
+
+	store.Set(ctx, "key", "value", time.Minute)
+
+	// Missing keys are not failures
+	for i := 0; i < 5; i++ {
+		store.Get(ctx, "missing")
+	}
+	if store.State() != cache.CircuitClosed {
+		t.Fatalf("Expected misses to keep the circuit closed, got %s", store.State())
+	}
+
+	flaky.down.Store(true)
+	for i := 0; i < 3; i++ {
+		if _, err := store.Get(ctx, "key"); !errors.Is(err, errDown) {
+			t.Fatalf("Expected store error before tripping, got %v", err)
+		}
+	}
+	if store.State() != cache.CircuitOpen {
+		t.Fatalf("Expected circuit to open after 3 failures, got %s", store.State())
+	}
+
+	// Open circuits fail fast without calling the store
+	calls := flaky.calls.Load()
+	if _, err := store.Get(ctx, "key"); !errors.Is(err, cache.ErrRedisUnavailable) {
+		t.Errorf("Expected ErrRedisUnavailable, got %v", err)
+	}
+	if err := store.Set(ctx, "key", "value", time.Minute); !errors.Is(err, cache.ErrRedisUnavailable) {
+		t.Errorf("Expected ErrRedisUnavailable, got %v", err)
+	}
+	if flaky.calls.Load() != calls {
+		t.Error("Expected open circuit not to call the store")
+	}
+
+	// Failed probes keep it open
+	time.Sleep(50 * time.Millisecond)
+	if store.State() == cache.CircuitClosed {
+		t.Fatal("Expected circuit to stay open while probes fail")
+	}
+
+	flaky.down.Store(false)
+	time.Sleep(50 * time.Millisecond)
+	if store.State() != cache.CircuitClosed {
+		t.Fatalf("Expected a successful probe to close the circuit, got %s", store.State())
+	}
+	if value, err := store.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("Expected value after recovery, got %v (%v)", value, err)
+	}
+
+	changes.mu.Lock()
+	states := append([]cache.CircuitState(nil), changes.states...)
+	changes.mu.Unlock()
+
+	seen := map[cache.CircuitState]bool{}
+	for _, state := range states {
+		seen[state] = true
+	}
+	if !seen[cache.CircuitOpen] || !seen[cache.CircuitHalfOpen] || states[len(states)-1] != cache.CircuitClosed {
+		t.Errorf("Expected open, half-open and closed transitions, got %v", states)
+	}
+}
+
+func TestCircuitBreakerFallback(t *testing.T) {
+	ctx := context.Background()
+
+	flaky := &flakyStore{MemoryStore: cache.NewMemoryStore(5 * time.Millisecond)}
+	store := cache.NewCircuitBreakerStore(flaky, cache.CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Minute,
+		Fallback:         cache.NewMemoryStore(time.Minute),
+	})
+	defer store.Close()
+
+	store.Set(ctx, "short", "value", time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	if store.Expirations() != 1 {
+		t.Fatalf("Expected 1 expiration, got %d", store.Expirations())
+	}
+
+	flaky.down.Store(true)
+	store.Get(ctx, "key")
+
+	// Counters keep their last value instead of dropping to zero
+	if store.Expirations() != 1 {
+		t.Errorf("Expected the last expiration count while open, got %d", store.Expirations())
+	}
+
+	if err := store.Set(ctx, "key", "local", time.Minute); err != nil {
+		t.Fatalf("Expected fallback to accept writes, got %v", err)
+	}
+	if value, err := store.Get(ctx, "key"); err != nil || value != "local" {
+		t.Errorf("Expected value from fallback, got %v (%v)", value, err)
+	}
+
+	// Deletes reach the fallback but fail, since Redis still has the key
+	if err := store.Delete(ctx, "key"); !errors.Is(err, cache.ErrRedisUnavailable) {
+		t.Errorf("Expected ErrRedisUnavailable from Delete, got %v", err)
+	}
+	if _, err := store.Get(ctx, "key"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected the fallback copy to be deleted, got %v", err)
+	}
+	if _, err := store.InvalidateTags(ctx, "group"); !errors.Is(err, cache.ErrRedisUnavailable) {
+		t.Errorf("Expected ErrRedisUnavailable from InvalidateTags, got %v", err)
+	}
+
+	// Locks are never taken locally
+	if _, _, err := store.AcquireLock(ctx, "job", "owner", time.Minute); !errors.Is(err, cache.ErrRedisUnavailable) {
+		t.Errorf("Expected ErrRedisUnavailable for locks, got %v", err)
+	}
+}
+
+func TestCircuitBreakerConfig(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig().
+		WithBackend(cache.BackendRedis).
+		WithRedisURL(unreachableRedis).
+		WithRedisOptions(cache.RedisOptions{
+			DialTimeout: 50 * time.Millisecond,
+			MaxRetries:  -1,
+			LazyConnect: true,
+		}).
+		WithCircuitBreaker(1, time.Minute).
+		WithCircuitBreakerFallback())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	var opened atomic.Bool
+	c.OnCircuitChange(func(from, to cache.CircuitState) {
+		if to == cache.CircuitOpen {
+			opened.Store(true)
+		}
+	})
+
+	if _, err := c.Get(ctx, "key"); err == nil {
+		t.Fatal("Expected the first call to reach the unreachable server")
+	}
+	if !opened.Load() {
+		t.Fatal("Expected OnCircuitChange to report the open circuit")
+	}
+
+	value, err := c.GetOrSet(ctx, "key", func() (interface{}, error) {
+		return "fetched", nil
+	}, time.Minute)
+	if err != nil || value != "fetched" {
+		t.Fatalf("Expected GetOrSet to work on the fallback, got %v (%v)", value, err)
+	}
+	if value, err := c.Get(ctx, "key"); err != nil || value != "fetched" {
+		t.Errorf("Expected cached value from the fallback, got %v (%v)", value, err)
+	}
+}
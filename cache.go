@@ -3,14 +3,32 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// lockPollInterval is how often a waiting instance re-checks the cache
+// while another instance holds the stampede lock
+const lockPollInterval = 50 * time.Millisecond
+
 // Cache is the main cache client
 type Cache struct {
-	store      Store
-	defaultTTL time.Duration
+	store        Store
+	defaultTTL   time.Duration
+	flight       *flightGroup
+	revalidating *sync.Map
+	refresh      *refresher
+	redis        *RedisStore
+	lockTTL      time.Duration
+	negativeTTL  time.Duration
+	codec        Codec
+	prefix       string
+	stats        *statsRecorder
+	events       *eventHooks
+	backend      Backend
 }
 
 // New creates a new cache instance
@@ -20,83 +38,343 @@ func New(config *Config) (*Cache, error) {
 	}
 
 	var store Store
+	var redisStore *RedisStore
 	var err error
 
+	// Memory stores report evictions and expirations to the cache hooks
+	events := &eventHooks{}
+
 	switch config.Backend {
 	case BackendMemory:
-		store = NewMemoryStore(config.CleanupInterval)
+		store = newMemoryBackend(config, config.MaxEntries, events)
 
 	case BackendRedis:
-		if config.RedisURL == "" {
-			return nil, fmt.Errorf("RedisURL is required for Redis backend")
+		redisStore, err = newRedisBackend(config)
+		if err != nil {
+			return nil, err
 		}
-		store, err = NewRedisStore(config.RedisURL)
+		store = withCircuitBreaker(redisStore, config, events)
+
+	case BackendTiered:
+		redisStore, err = newRedisBackend(config)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create Redis store: %w", err)
+			return nil, err
+		}
+
+		l1TTL := config.L1TTL
+		if l1TTL <= 0 {
+			l1TTL = defaultL1TTL
+		}
+
+		l1 := newMemoryBackend(config, boundedMaxEntries(config), events)
+		l2 := withCircuitBreaker(redisStore, config, events)
+		tiered := NewTieredStore(l1, l2, l1TTL)
+
+		if config.InvalidationChannel != "" {
+			bus, err := NewInvalidationBus(redisStore, l1, config.InvalidationChannel)
+			if err != nil {
+				// Closes the circuit breaker fallback along with Redis
+				l2.Close()
+				l1.Close()
+				return nil, fmt.Errorf("failed to create invalidation bus: %w", err)
+			}
+			tiered.WithInvalidation(bus)
 		}
+		store = tiered
 
 	default:
 		return nil, fmt.Errorf("unsupported backend: %s", config.Backend)
 	}
 
+	if len(config.EncryptionKeys) > 0 {
+		encrypted, err := NewEncryptedStore(store, config.EncryptionKeys, config.EncryptionKeyID)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		store = encrypted
+	}
+
+	// Compression wraps encryption because ciphertext doesn't compress
+	if config.Compression != CompressionNone {
+		compressed, err := NewCompressedStore(store, config.Compression, config.CompressionThreshold)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		store = compressed
+	}
+
+	return newCache(store, redisStore, config, events), nil
+}
+
+// NewWithStore creates a cache on top of an existing store, such as a custom
+// backend or a store wrapped in instrumentation. Backend, connection and
+// store wrapper settings in config are ignored. OnEvict and OnExpire
+// callbacks are not fired; set MemoryOptions.OnEvict and OnExpire on the
+// store instead.
+func NewWithStore(store Store, config *Config) *Cache {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	redisStore, _ := findStore[*RedisStore](store)
+	return newCache(store, redisStore, config, &eventHooks{})
+}
+
+// newCache creates a cache around a fully assembled store
+func newCache(store Store, redisStore *RedisStore, config *Config, events *eventHooks) *Cache {
+	negativeTTL := config.NegativeTTL
+	if negativeTTL <= 0 {
+		negativeTTL = defaultNegativeTTL
+	}
+
 	return &Cache{
-		store:      store,
-		defaultTTL: config.DefaultTTL,
-	}, nil
+		store:        store,
+		defaultTTL:   config.DefaultTTL,
+		flight:       &flightGroup{},
+		revalidating: &sync.Map{},
+		refresh:      newRefresher(config.RefreshWorkers),
+		redis:        redisStore,
+		lockTTL:      config.StampedeLockTTL,
+		negativeTTL:  negativeTTL,
+		codec:        config.Codec,
+		stats:        &statsRecorder{},
+		events:       events,
+		backend:      config.Backend,
+	}
+}
+
+// newMemoryBackend creates a plain or sharded MemoryStore from the config
+// that reports evictions and expirations to events
+func newMemoryBackend(config *Config, maxEntries int, events *eventHooks) Store {
+	opts := MemoryOptions{
+		CleanupInterval: config.CleanupInterval,
+		CleanupBudget:   config.CleanupBudget,
+		MaxEntries:      maxEntries,
+		MaxBytes:        config.MaxBytes,
+		MaxItemBytes:    config.MaxItemBytes,
+		Sizer:           config.Sizer,
+		EvictionPolicy:  config.EvictionPolicy,
+		OnEvict:         events.evicted,
+		OnExpire:        events.expired,
+	}
+
+	if config.Shards > 1 {
+		return NewShardedMemoryStore(config.Shards, opts)
+	}
+	return NewMemoryStoreWithOptions(opts)
+}
+
+// boundedMaxEntries returns the entry limit for local stores that sit next to
+// Redis, such as the L1 tier and the circuit breaker fallback. These must
+// always be bounded, so defaultL1MaxEntries applies when no limit is set.
+func boundedMaxEntries(config *Config) int {
+	if config.MaxEntries <= 0 && config.MaxBytes <= 0 {
+		return defaultL1MaxEntries
+	}
+	return config.MaxEntries
+}
+
+// withCircuitBreaker wraps the Redis store in a circuit breaker if the config enables one
+func withCircuitBreaker(redisStore *RedisStore, config *Config, events *eventHooks) Store {
+	if config.CircuitBreakerThreshold <= 0 {
+		return redisStore
+	}
+
+	opts := CircuitBreakerOptions{
+		FailureThreshold: config.CircuitBreakerThreshold,
+		OpenTimeout:      config.CircuitBreakerTimeout,
+		OnStateChange:    events.circuitChanged,
+	}
+	if config.CircuitBreakerFallback {
+		opts.Fallback = newMemoryBackend(config, boundedMaxEntries(config), events)
+	}
+	return NewCircuitBreakerStore(redisStore, opts)
+}
+
+// newRedisBackend creates a RedisStore from the config
+func newRedisBackend(config *Config) (*RedisStore, error) {
+	if config.RedisURL == "" && len(config.RedisAddrs) == 0 {
+		return nil, fmt.Errorf("RedisURL is required for %s backend", config.Backend)
+	}
+
+	client, err := newRedisClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var store *RedisStore
+	if config.Redis.LazyConnect {
+		store = newRedisStore(client)
+	} else {
+		pingTimeout := config.Redis.PingTimeout
+		if pingTimeout <= 0 {
+			pingTimeout = defaultPingTimeout
+		}
+		store, err = connectRedisStore(client, pingTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Redis store: %w", err)
+		}
+	}
+
+	if config.AllowFlush {
+		store.WithFlush()
+	}
+	return store, nil
 }
 
 // Get retrieves a value from the cache
 func (c *Cache) Get(ctx context.Context, key string) (interface{}, error) {
-	return c.store.Get(ctx, key)
+	value, err := c.getRaw(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.decode(value)
+}
+
+// getRaw retrieves a value as stored, without decoding it
+func (c *Cache) getRaw(ctx context.Context, key string) (interface{}, error) {
+	value, _, err := c.getEntry(ctx, key)
+	return value, err
+}
+
+// getEntry retrieves a value as stored, without decoding it, and the time it
+// goes stale if it was written by GetOrSetStale
+func (c *Cache) getEntry(ctx context.Context, key string) (interface{}, time.Time, error) {
+	defer c.stats.observe(OpGet, time.Now())
+
+	value, err := c.store.Get(ctx, key)
+	if err != nil {
+		atomic.AddUint64(&c.stats.misses, 1)
+		c.events.miss(ctx, c.prefix+key)
+		c.events.failed(ctx, OpGet, c.prefix+key, err)
+		return nil, time.Time{}, err
+	}
+
+	// Keys cached as not existing are misses
+	if isNegative(value) {
+		atomic.AddUint64(&c.stats.misses, 1)
+		c.events.miss(ctx, c.prefix+key)
+		return nil, time.Time{}, ErrNegativeCached
+	}
+
+	atomic.AddUint64(&c.stats.hits, 1)
+	c.events.hit(ctx, c.prefix+key)
+	c.refresh.accessed(c.prefix + key)
+
+	value, freshUntil, _ := unwrapStale(value)
+	return value, freshUntil, nil
 }
 
 // Set stores a value in the cache with default TTL
 func (c *Cache) Set(ctx context.Context, key string, value interface{}) error {
-	return c.store.Set(ctx, key, value, c.defaultTTL)
+	return c.SetWithTTL(ctx, key, value, c.defaultTTL)
 }
 
 // SetWithTTL stores a value in the cache with custom TTL
 func (c *Cache) SetWithTTL(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	return c.store.Set(ctx, key, value, ttl)
+	encoded, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+
+	return c.setEncoded(ctx, key, value, encoded, refreshWrite{ttl: ttl})
+}
+
+// setEncoded stores an encoded value with write.ttl; value is the original
+// passed to OnSet callbacks, and write tells refreshes how to rewrite it
+func (c *Cache) setEncoded(ctx context.Context, key string, value, encoded interface{}, write refreshWrite) error {
+	defer c.stats.observe(OpSet, time.Now())
+	if err := c.store.Set(ctx, key, encoded, write.ttl); err != nil {
+		c.events.failed(ctx, OpSet, c.prefix+key, err)
+		return err
+	}
+
+	atomic.AddUint64(&c.stats.sets, 1)
+	c.events.set(ctx, c.prefix+key, value, write.ttl)
+	c.refresh.written(c.prefix+key, write)
+	return nil
 }
 
 // Delete removes a value from the cache
 func (c *Cache) Delete(ctx context.Context, key string) error {
-	return c.store.Delete(ctx, key)
+	defer c.stats.observe(OpDelete, time.Now())
+
+	if err := c.store.Delete(ctx, key); err != nil {
+		c.events.failed(ctx, OpDelete, c.prefix+key, err)
+		return err
+	}
+
+	atomic.AddUint64(&c.stats.deletes, 1)
+	c.refresh.forget(c.prefix + key)
+	return nil
 }
 
-// Has checks if a key exists
+// Has checks if a key exists. Keys cached as not existing are reported as
+// missing, like Get does; telling them apart requires reading the value.
 func (c *Cache) Has(ctx context.Context, key string) bool {
-	return c.store.Has(ctx, key)
+	value, err := c.store.Get(ctx, key)
+	return err == nil && !isNegative(value)
 }
 
 // Increment increments a numeric value
 func (c *Cache) Increment(ctx context.Context, key string, delta int64) (int64, error) {
-	return c.store.Increment(ctx, key, delta)
+	defer c.stats.observe(OpIncrement, time.Now())
+
+	value, err := c.store.Increment(ctx, key, delta)
+	if err != nil {
+		c.events.failed(ctx, OpIncrement, c.prefix+key, err)
+	}
+	return value, err
 }
 
 // Decrement decrements a numeric value
 func (c *Cache) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
-	return c.store.Decrement(ctx, key, delta)
+	defer c.stats.observe(OpDecrement, time.Now())
+
+	value, err := c.store.Decrement(ctx, key, delta)
+	if err != nil {
+		c.events.failed(ctx, OpDecrement, c.prefix+key, err)
+	}
+	return value, err
 }
 
-// Clear removes all entries
+// Clear removes all entries. On a namespaced cache only keys in the
+// namespace are removed; on the root cache of a Redis or tiered backend
+// the whole database is flushed, which requires Config.AllowFlush.
 func (c *Cache) Clear(ctx context.Context) error {
+	c.refresh.forgetPrefix(c.prefix)
 	return c.store.Clear(ctx)
 }
 
-// Close closes the cache connection
+// Close stops refresh-ahead loaders and closes the cache connection
 func (c *Cache) Close() error {
+	// Namespaces share the refresher of the root cache, which owns it
+	if c.prefix == "" {
+		c.refresh.close()
+	}
 	return c.store.Close()
 }
 
 // GetJSON retrieves and unmarshals JSON data
 func (c *Cache) GetJSON(ctx context.Context, key string, dest interface{}) error {
-	value, err := c.Get(ctx, key)
+	return c.GetInto(ctx, key, dest)
+}
+
+// GetInto retrieves a value and decodes it into dest, using the codec the
+// value was written with, or JSON for values written without one
+func (c *Cache) GetInto(ctx context.Context, key string, dest interface{}) error {
+	value, err := c.getRaw(ctx, key)
 	if err != nil {
 		return err
 	}
 
+	if decoded, err := c.decodeEncoded(value, dest); decoded {
+		return err
+	}
+	value = unescapeRaw(value)
+
 	// If it's already a string (from Redis), unmarshal it
 	if str, ok := value.(string); ok {
 		return json.Unmarshal([]byte(str), dest)
@@ -132,22 +410,77 @@ func (c *Cache) SetJSONWithTTL(ctx context.Context, key string, value interface{
 	return c.SetWithTTL(ctx, key, value, ttl)
 }
 
-// GetOrSet retrieves a value or sets it if not found (cache-aside pattern)
+// storedValue marks a value read back from the store, as opposed to one
+// freshly returned by a fetcher, so that only stored values get decoded
+type storedValue struct {
+	value interface{}
+}
+
+// GetOrSet retrieves a value or sets it if not found (cache-aside pattern).
+// Concurrent misses for the same key share a single fetcher call; each
+// caller stops waiting as soon as its own context is done.
 func (c *Cache) GetOrSet(ctx context.Context, key string, fetcher func() (interface{}, error), ttl time.Duration) (interface{}, error) {
+	value, err := c.getOrSet(ctx, key, fetcher, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored, ok := value.(storedValue); ok {
+		return c.decode(stored.value)
+	}
+	return value, nil
+}
+
+// getOrSet returns either a storedValue or the value returned by the fetcher
+func (c *Cache) getOrSet(ctx context.Context, key string, fetcher func() (interface{}, error), ttl time.Duration) (interface{}, error) {
 	// Try to get from cache
-	value, err := c.Get(ctx, key)
+	value, err := c.getRaw(ctx, key)
 	if err == nil {
-		return value, nil
+		return storedValue{value}, nil
+	}
+	if errors.Is(err, ErrNegativeCached) {
+		return nil, err
+	}
+
+	// Not in cache, fetch it once for all waiting callers
+	return c.flight.do(ctx, key, func() (interface{}, error) {
+		return c.load(context.WithoutCancel(ctx), key, fetcher, func(ctx context.Context, value interface{}) error {
+			return c.SetWithTTL(ctx, key, value, ttl)
+		})
+	})
+}
+
+// load runs the fetcher for a missed key and stores the result with set
+func (c *Cache) load(ctx context.Context, key string, fetcher func() (interface{}, error), set func(ctx context.Context, value interface{}) error) (interface{}, error) {
+	if c.lockTTL > 0 && c.redis != nil {
+		value, unlock, err := c.acquireLoadLock(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if unlock == nil {
+			// Another instance loaded the value while we waited
+			return value, nil
+		}
+		defer unlock()
 	}
 
-	// Not in cache, fetch it
-	value, err = fetcher()
+	atomic.AddUint64(&c.stats.loaderCalls, 1)
+	start := time.Now()
+	value, err := fetcher()
+	c.stats.observe(OpLoad, start)
+	if errors.Is(err, ErrNotExist) {
+		// Remember the miss so repeated lookups don't reach the fetcher
+		c.setNegative(ctx, key)
+		return nil, err
+	}
 	if err != nil {
+		atomic.AddUint64(&c.stats.loaderErrors, 1)
+		c.events.failed(ctx, OpLoad, c.prefix+key, err)
 		return nil, err
 	}
 
 	// Store in cache
-	if err := c.SetWithTTL(ctx, key, value, ttl); err != nil {
+	if err := set(ctx, value); err != nil {
 		// Log error but don't fail - we have the value
 		return value, nil
 	}
@@ -155,6 +488,65 @@ func (c *Cache) GetOrSet(ctx context.Context, key string, fetcher func() (interf
 	return value, nil
 }
 
+// acquireLoadLock coordinates fetching across instances with a short Redis
+// lease, kept apart from Locker locks on the same key. It returns an unlock
+// function once the lease is held, or the cached value if another instance
+// stored it in the meantime.
+func (c *Cache) acquireLoadLock(ctx context.Context, key string) (interface{}, func(), error) {
+	leases, ok := findStore[leaseStore](c.store)
+	if !ok {
+		return nil, func() {}, nil
+	}
+	owner, err := newLockOwner()
+	if err != nil {
+		return nil, nil, err
+	}
+	leaseKey := c.prefix + key
+
+	for {
+		held, err := leases.acquireLease(ctx, leaseKey, owner, c.lockTTL)
+		if err != nil {
+			// Redis trouble - fall back to in-process coalescing only
+			return nil, func() {}, nil
+		}
+		if held {
+			unlock := func() { leases.releaseLease(ctx, leaseKey, owner) }
+
+			// The previous holder may have stored the value since the last poll
+			if value, found, err := c.loaded(ctx, key); found {
+				unlock()
+				return value, nil, err
+			}
+			return nil, unlock, nil
+		}
+
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+
+		if value, found, err := c.loaded(ctx, key); found {
+			return value, nil, err
+		}
+	}
+}
+
+// loaded checks whether another instance stored key while acquireLoadLock
+// waited, returning the value or ErrNegativeCached. It bypasses getRaw so
+// the checks don't count as misses.
+func (c *Cache) loaded(ctx context.Context, key string) (interface{}, bool, error) {
+	value, err := c.store.Get(ctx, key)
+	if err != nil {
+		return nil, false, nil
+	}
+	if isNegative(value) {
+		return nil, true, ErrNegativeCached
+	}
+	value, _, _ = unwrapStale(value)
+	return storedValue{value}, true, nil
+}
+
 // Remember is an alias for GetOrSet with default TTL
 func (c *Cache) Remember(ctx context.Context, key string, fetcher func() (interface{}, error)) (interface{}, error) {
 	return c.GetOrSet(ctx, key, fetcher, c.defaultTTL)
@@ -162,15 +554,19 @@ func (c *Cache) Remember(ctx context.Context, key string, fetcher func() (interf
 
 // Forever stores a value with no expiration
 func (c *Cache) Forever(ctx context.Context, key string, value interface{}) error {
-	return c.store.Set(ctx, key, value, 0)
+	return c.SetWithTTL(ctx, key, value, 0)
 }
 
-// GetMany retrieves multiple values at once
+// GetMany retrieves multiple values at once. Keys that cannot be read are
+// left out, but values needing a destination type fail with ErrCodecRequiresDest.
 func (c *Cache) GetMany(ctx context.Context, keys []string) (map[string]interface{}, error) {
 	results := make(map[string]interface{})
 
 	for _, key := range keys {
 		value, err := c.Get(ctx, key)
+		if errors.Is(err, ErrCodecRequiresDest) {
+			return nil, err
+		}
 		if err == nil {
 			results[key] = value
 		}
@@ -199,6 +595,11 @@ func (c *Cache) DeleteMany(ctx context.Context, keys []string) error {
 	return nil
 }
 
+// Backend returns the storage backend the cache was created with
+func (c *Cache) Backend() Backend {
+	return c.backend
+}
+
 // GetStore returns the underlying store for advanced operations
 func (c *Cache) GetStore() Store {
 	return c.store
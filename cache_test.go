@@ -2,6 +2,9 @@ package cache_test
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -245,3 +248,54 @@ func TestMultiOperations(t *testing.T) {
 		t.Error("key3 should still exist")
 	}
 }
+
+func TestGetOrSetStampede(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(&cache.Config{
+		Backend: cache.BackendMemory,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	var fetchCount int32
+	release := make(chan struct{})
+	fetcher := func() (interface{}, error) {
+		atomic.AddInt32(&fetchCount, 1)
+		<-release
+		return "fetched_value", nil
+	}
+
+	// Concurrent misses should share one fetch
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.GetOrSet(ctx, "hot_key", fetcher, 1*time.Hour)
+			if err != nil {
+				t.Errorf("GetOrSet failed: %v", err)
+			}
+			if value != "fetched_value" {
+				t.Errorf("Expected fetched_value, got %v", value)
+			}
+		}()
+	}
+
+	// A waiter with a cancelled context should return early
+	cancelCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = c.GetOrSet(cancelCtx, "hot_key", fetcher, 1*time.Hour)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&fetchCount); n != 1 {
+		t.Errorf("Expected fetch count 1, got %d", n)
+	}
+}
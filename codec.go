@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+)
+
+// Header bytes prepended to encoded values. They are UTF-8 continuation bytes,
+// which can never start a valid UTF-8 string, so encoded values are always
+// distinguishable from plain strings written without a codec.
+const (
+	codecHeaderJSON   byte = 0x81
+	codecHeaderGob    byte = 0x82
+	codecHeaderBinary byte = 0x83
+)
+
+// rawHeader escapes raw strings and byte slices that start with a header
+// byte, so they are not mistaken for encoded values when read back
+const rawHeader byte = 0x80
+
+// ErrCodecRequiresDest is returned by Get, GetOrSet and GetMany for values
+// written with a codec that cannot decode without knowing the target type
+var ErrCodecRequiresDest = errors.New("codec requires a destination type; use GetInto or a TypedCache")
+
+// Codec serializes values before they reach the store
+type Codec interface {
+	// ID is the header byte written in front of every encoded value, so
+	// values can be decoded even after the configured codec changes.
+	// Custom codecs should use a byte from 0x84 to 0x8a.
+	ID() byte
+
+	// Marshal encodes a value
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decodes data into the value pointed to by v
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec encodes values as JSON
+type JSONCodec struct{}
+
+// ID returns the JSON header byte
+func (JSONCodec) ID() byte { return codecHeaderJSON }
+
+// Marshal encodes a value as JSON
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes JSON data
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec encodes values with encoding/gob, preserving Go types exactly.
+// Gob streams carry no type for the top-level value, so gob-encoded values
+// must be read with GetInto or a TypedCache: Get, GetOrSet hits and GetMany
+// fail with ErrCodecRequiresDest.
+type GobCodec struct{}
+
+// ID returns the gob header byte
+func (GobCodec) ID() byte { return codecHeaderGob }
+
+// Marshal encodes a value with gob
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes gob data
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// builtinCodecs maps header bytes to the codecs shipped with this package
+var builtinCodecs = map[byte]Codec{
+	codecHeaderJSON:   JSONCodec{},
+	codecHeaderGob:    GobCodec{},
+	codecHeaderBinary: BinaryCodec{},
+}
+
+// asBytes returns the raw bytes of string and []byte values
+func asBytes(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	}
+	return nil, false
+}
+
+// isHeader reports whether b is in the range reserved for header bytes
+func isHeader(b byte) bool {
+	return b&0xf0 == 0x80
+}
+
+// escapeRaw prefixes strings and byte slices starting with a header byte with rawHeader
+func escapeRaw(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		if len(v) > 0 && isHeader(v[0]) {
+			return append([]byte{rawHeader}, v...)
+		}
+	case string:
+		if len(v) > 0 && isHeader(v[0]) {
+			return string([]byte{rawHeader}) + v
+		}
+	}
+	return value
+}
+
+// unescapeRaw removes the rawHeader added by escapeRaw, keeping the value's type
+func unescapeRaw(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		if len(v) > 0 && v[0] == rawHeader {
+			return v[1:]
+		}
+	case string:
+		if len(v) > 0 && v[0] == rawHeader {
+			return v[1:]
+		}
+	}
+	return value
+}
+
+// encode serializes a value with the configured codec, if any.
+// Without a codec, raw values that look encoded are escaped.
+func (c *Cache) encode(value interface{}) (interface{}, error) {
+	if c.codec == nil {
+		return escapeRaw(value), nil
+	}
+
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := make([]byte, len(data)+1)
+	encoded[0] = c.codec.ID()
+	copy(encoded[1:], data)
+	return encoded, nil
+}
+
+// codecFor returns the codec that encoded data, if it carries a known header
+func (c *Cache) codecFor(data []byte) Codec {
+	if len(data) == 0 {
+		return nil
+	}
+	if codec, found := builtinCodecs[data[0]]; found {
+		return codec
+	}
+	if c.codec != nil && c.codec.ID() == data[0] {
+		return c.codec
+	}
+	return nil
+}
+
+// decode deserializes an encoded value into its generic form.
+// Values written without a codec are returned as written.
+func (c *Cache) decode(value interface{}) (interface{}, error) {
+	data, ok := asBytes(value)
+	if !ok {
+		return value, nil
+	}
+
+	codec := c.codecFor(data)
+	if codec == nil {
+		return unescapeRaw(value), nil
+	}
+	if codec.ID() == codecHeaderGob {
+		return nil, ErrCodecRequiresDest
+	}
+
+	var decoded interface{}
+	if err := codec.Unmarshal(data[1:], &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// decodeEncoded decodes a codec-encoded value into dest.
+// It reports false if the value was not written with a codec; pass such
+// values through unescapeRaw before using them.
+func (c *Cache) decodeEncoded(value interface{}, dest interface{}) (bool, error) {
+	data, ok := asBytes(value)
+	if !ok {
+		return false, nil
+	}
+
+	codec := c.codecFor(data)
+	if codec == nil {
+		return false, nil
+	}
+
+	return true, codec.Unmarshal(data[1:], dest)
+}
@@ -0,0 +1,742 @@
+package cache
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Extension types used by BinaryCodec
+const (
+	binaryExtMarshaler int8 = 1
+	binaryExtTime      int8 = 2
+)
+
+var (
+	errBinaryTruncated = errors.New("cache: truncated binary value")
+
+	timeType            = reflect.TypeOf(time.Time{})
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+)
+
+// BinaryCodec is a compact MessagePack-style codec. It keeps integers,
+// unsigned integers and floats distinct, stores byte slices as raw binary,
+// and encodes time.Time with its zone offset. Structs are encoded as maps
+// keyed by their JSON field names.
+type BinaryCodec struct{}
+
+// binaryExt is an extension value whose Go type is unknown to the decoder
+type binaryExt []byte
+
+// ID returns the binary header byte
+func (BinaryCodec) ID() byte { return codecHeaderBinary }
+
+// Marshal encodes a value in the binary format
+func (BinaryCodec) Marshal(v interface{}) ([]byte, error) {
+	e := &binaryEncoder{}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// Unmarshal decodes binary data into the value pointed to by v
+func (BinaryCodec) Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("cache: Unmarshal requires a non-nil pointer, got %T", v)
+	}
+
+	d := &binaryDecoder{data: data}
+	value, err := d.readValue()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(d.data) {
+		return fmt.Errorf("cache: %d trailing bytes after binary value", len(d.data)-d.pos)
+	}
+
+	return assignBinary(rv.Elem(), value)
+}
+
+// binaryEncoder appends MessagePack-style values to a buffer
+type binaryEncoder struct {
+	buf []byte
+}
+
+func (e *binaryEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf = append(e.buf, 0xc0)
+		return nil
+	}
+
+	if v.Type() == timeType {
+		data, err := v.Interface().(time.Time).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		e.writeExt(binaryExtTime, data)
+		return nil
+	}
+
+	if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface && v.Type().Implements(binaryMarshalerType) {
+		data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		e.writeExt(binaryExtMarshaler, data)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		return e.encode(v.Elem())
+
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf = append(e.buf, 0xc3)
+		} else {
+			e.buf = append(e.buf, 0xc2)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(v.Int())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(v.Uint())
+
+	case reflect.Float32:
+		e.buf = append(e.buf, 0xca)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, math.Float32bits(float32(v.Float())))
+
+	case reflect.Float64:
+		e.buf = append(e.buf, 0xcb)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v.Float()))
+
+	case reflect.String:
+		e.writeString(v.String())
+
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.writeBin(v.Bytes())
+			return nil
+		}
+		return e.encodeArray(v)
+
+	case reflect.Array:
+		return e.encodeArray(v)
+
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf = append(e.buf, 0xc0)
+			return nil
+		}
+		e.writeHeader(v.Len(), 0x80, 0xde, 0xdf)
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := e.encode(iter.Key()); err != nil {
+				return err
+			}
+			if err := e.encode(iter.Value()); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		fields := binaryFields(v.Type())
+		e.writeHeader(len(fields), 0x80, 0xde, 0xdf)
+		for _, field := range fields {
+			e.writeString(field.name)
+			if err := e.encode(v.Field(field.index)); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("cache: binary codec cannot encode %s", v.Type())
+	}
+
+	return nil
+}
+
+func (e *binaryEncoder) encodeArray(v reflect.Value) error {
+	e.writeHeader(v.Len(), 0x90, 0xdc, 0xdd)
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader writes an array or map header using the fix, 16-bit or 32-bit form
+func (e *binaryEncoder) writeHeader(n int, fix, code16, code32 byte) {
+	switch {
+	case n <= 15:
+		e.buf = append(e.buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, code16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, code32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *binaryEncoder) writeUint(u uint64) {
+	switch {
+	case u <= 0x7f:
+		e.buf = append(e.buf, byte(u))
+	case u <= math.MaxUint8:
+		e.buf = append(e.buf, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		e.buf = append(e.buf, 0xcd)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(u))
+	case u <= math.MaxUint32:
+		e.buf = append(e.buf, 0xce)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(u))
+	default:
+		e.buf = append(e.buf, 0xcf)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, u)
+	}
+}
+
+func (e *binaryEncoder) writeInt(i int64) {
+	switch {
+	case i >= 0:
+		e.writeUint(uint64(i))
+	case i >= -32:
+		e.buf = append(e.buf, byte(int8(i)))
+	case i >= math.MinInt8:
+		e.buf = append(e.buf, 0xd0, byte(int8(i)))
+	case i >= math.MinInt16:
+		e.buf = append(e.buf, 0xd1)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(int16(i)))
+	case i >= math.MinInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(int32(i)))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(i))
+	}
+}
+
+func (e *binaryEncoder) writeString(s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xda)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, 0xdb)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *binaryEncoder) writeBin(b []byte) {
+	e.writeLength(len(b), 0xc4, 0xc5, 0xc6)
+	e.buf = append(e.buf, b...)
+}
+
+func (e *binaryEncoder) writeExt(typ int8, data []byte) {
+	e.writeLength(len(data), 0xc7, 0xc8, 0xc9)
+	e.buf = append(e.buf, byte(typ))
+	e.buf = append(e.buf, data...)
+}
+
+// writeLength writes an 8, 16 or 32-bit length prefix
+func (e *binaryEncoder) writeLength(n int, code8, code16, code32 byte) {
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, code8, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, code16)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	default:
+		e.buf = append(e.buf, code32)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	}
+}
+
+// binaryField is an exported struct field and its encoded name
+type binaryField struct {
+	name  string
+	index int
+}
+
+// binaryFields lists the exported fields of a struct, named like encoding/json would
+func binaryFields(t reflect.Type) []binaryField {
+	fields := make([]binaryField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		fields = append(fields, binaryField{name: name, index: i})
+	}
+	return fields
+}
+
+// binaryDecoder reads MessagePack-style values into generic Go values
+type binaryDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *binaryDecoder) read(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errBinaryTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *binaryDecoder) readUint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// readValue decodes the next value. Integers decode to int64 (or uint64 when
+// they don't fit), maps with string keys to map[string]interface{}, and
+// arrays to []interface{}.
+func (d *binaryDecoder) readValue() (interface{}, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return d.readMap(int(code & 0x0f))
+	case code&0xf0 == 0x90:
+		return d.readArray(int(code & 0x0f))
+	case code&0xe0 == 0xa0:
+		return d.readString(int(code & 0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readUint(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.read(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), data...), nil
+
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readUint(1 << (code - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.readExt(int(n))
+
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.readExt(1 << (code - 0xd4))
+
+	case 0xca:
+		u, err := d.readUint(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(uint32(u))), nil
+
+	case 0xcb:
+		u, err := d.readUint(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(u), nil
+
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.readUint(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if u > math.MaxInt64 {
+			return u, nil
+		}
+		return int64(u), nil
+
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		u, err := d.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+		switch size {
+		case 1:
+			return int64(int8(u)), nil
+		case 2:
+			return int64(int16(u)), nil
+		case 4:
+			return int64(int32(u)), nil
+		default:
+			return int64(u), nil
+		}
+
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readUint(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.readString(int(n))
+
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.readArray(int(n))
+
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.readMap(int(n))
+	}
+
+	return nil, fmt.Errorf("cache: invalid binary format byte 0x%02x", code)
+}
+
+func (d *binaryDecoder) readString(n int) (interface{}, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *binaryDecoder) readArray(n int) (interface{}, error) {
+	// Every element takes at least one byte, which bounds the allocation
+	if n > len(d.data)-d.pos {
+		return nil, errBinaryTruncated
+	}
+
+	values := make([]interface{}, n)
+	for i := range values {
+		value, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (d *binaryDecoder) readMap(n int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errBinaryTruncated
+	}
+
+	keys := make([]interface{}, n)
+	values := make([]interface{}, n)
+	allStrings := true
+	for i := 0; i < n; i++ {
+		key, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := key.(string); !ok {
+			allStrings = false
+		}
+		keys[i], values[i] = key, value
+	}
+
+	if allStrings {
+		m := make(map[string]interface{}, n)
+		for i, key := range keys {
+			m[key.(string)] = values[i]
+		}
+		return m, nil
+	}
+
+	m := make(map[interface{}]interface{}, n)
+	for i, key := range keys {
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			return nil, fmt.Errorf("cache: unhashable binary map key of type %T", key)
+		}
+		m[key] = values[i]
+	}
+	return m, nil
+}
+
+func (d *binaryDecoder) readExt(n int) (interface{}, error) {
+	typ, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+
+	if int8(typ[0]) == binaryExtTime {
+		var t time.Time
+		if err := t.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	return binaryExt(append([]byte(nil), data...)), nil
+}
+
+// assignBinary stores a generic decoded value into dst, converting between
+// numeric types and mapping maps onto structs
+func assignBinary(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if ext, ok := src.(binaryExt); ok {
+		if dst.CanAddr() {
+			if u, ok := dst.Addr().Interface().(encoding.BinaryUnmarshaler); ok {
+				return u.UnmarshalBinary(ext)
+			}
+		}
+		src = []byte(ext)
+	}
+
+	srcValue := reflect.ValueOf(src)
+	if dst.Kind() == reflect.Interface {
+		if !srcValue.Type().AssignableTo(dst.Type()) {
+			return fmt.Errorf("cache: cannot assign %T to %s", src, dst.Type())
+		}
+		dst.Set(srcValue)
+		return nil
+	}
+
+	if srcValue.Type() == dst.Type() && dst.Kind() != reflect.Map && dst.Kind() != reflect.Slice {
+		dst.Set(srcValue)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignBinary(dst.Elem(), src)
+
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return binaryMismatch(src, dst)
+		}
+		dst.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch v := src.(type) {
+		case int64:
+			i = v
+		case uint64:
+			return binaryMismatch(src, dst)
+		case float64:
+			if v != math.Trunc(v) {
+				return binaryMismatch(src, dst)
+			}
+			i = int64(v)
+		default:
+			return binaryMismatch(src, dst)
+		}
+		if dst.OverflowInt(i) {
+			return fmt.Errorf("cache: value %d overflows %s", i, dst.Type())
+		}
+		dst.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch v := src.(type) {
+		case int64:
+			if v < 0 {
+				return fmt.Errorf("cache: value %d overflows %s", v, dst.Type())
+			}
+			u = uint64(v)
+		case uint64:
+			u = v
+		default:
+			return binaryMismatch(src, dst)
+		}
+		if dst.OverflowUint(u) {
+			return fmt.Errorf("cache: value %d overflows %s", u, dst.Type())
+		}
+		dst.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		switch v := src.(type) {
+		case float64:
+			dst.SetFloat(v)
+		case int64:
+			dst.SetFloat(float64(v))
+		case uint64:
+			dst.SetFloat(float64(v))
+		default:
+			return binaryMismatch(src, dst)
+		}
+
+	case reflect.String:
+		switch v := src.(type) {
+		case string:
+			dst.SetString(v)
+		case []byte:
+			dst.SetString(string(v))
+		default:
+			return binaryMismatch(src, dst)
+		}
+
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() == reflect.Uint8 {
+			switch v := src.(type) {
+			case []byte:
+				dst.SetBytes(v)
+				return nil
+			case string:
+				dst.SetBytes([]byte(v))
+				return nil
+			}
+		}
+		items, ok := src.([]interface{})
+		if !ok {
+			return binaryMismatch(src, dst)
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := assignBinary(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+
+	case reflect.Array:
+		items, ok := src.([]interface{})
+		if !ok || len(items) != dst.Len() {
+			return binaryMismatch(src, dst)
+		}
+		for i, item := range items {
+			if err := assignBinary(dst.Index(i), item); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		m := reflect.MakeMap(dst.Type())
+		assignEntry := func(key, value interface{}) error {
+			k := reflect.New(dst.Type().Key()).Elem()
+			if err := assignBinary(k, key); err != nil {
+				return err
+			}
+			v := reflect.New(dst.Type().Elem()).Elem()
+			if err := assignBinary(v, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(k, v)
+			return nil
+		}
+
+		switch entries := src.(type) {
+		case map[string]interface{}:
+			for key, value := range entries {
+				if err := assignEntry(key, value); err != nil {
+					return err
+				}
+			}
+		case map[interface{}]interface{}:
+			for key, value := range entries {
+				if err := assignEntry(key, value); err != nil {
+					return err
+				}
+			}
+		default:
+			return binaryMismatch(src, dst)
+		}
+		dst.Set(m)
+
+	case reflect.Struct:
+		entries, ok := src.(map[string]interface{})
+		if !ok {
+			return binaryMismatch(src, dst)
+		}
+		for _, field := range binaryFields(dst.Type()) {
+			value, found := entries[field.name]
+			if !found {
+				continue
+			}
+			if err := assignBinary(dst.Field(field.index), value); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return binaryMismatch(src, dst)
+	}
+
+	return nil
+}
+
+func binaryMismatch(src interface{}, dst reflect.Value) error {
+	return fmt.Errorf("cache: cannot decode binary %T into %s", src, dst.Type())
+}
@@ -0,0 +1,213 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+type codecRecord struct {
+	ID      int64             `json:"id"`
+	Count   uint64            `json:"count"`
+	Score   float64           `json:"score"`
+	Name    string            `json:"name"`
+	Data    []byte            `json:"data"`
+	Tags    []string          `json:"tags"`
+	Attrs   map[string]int    `json:"attrs"`
+	Created time.Time         `json:"created"`
+	Parent  *codecRecord      `json:"parent,omitempty"`
+	Extra   map[string]string `json:"-"`
+}
+
+func newCodecRecord() codecRecord {
+	zone := time.FixedZone("UTC+3", 3*60*60)
+	return codecRecord{
+		ID:      1 << 60,
+		Count:   1<<64 - 1,
+		Score:   3.25,
+		Name:    "John",
+		Data:    []byte{0x00, 0x81, 0xff},
+		Tags:    []string{"a", "b"},
+		Attrs:   map[string]int{"x": -1, "y": 300},
+		Created: time.Date(2024, 5, 1, 12, 30, 0, 123, zone),
+		Parent:  &codecRecord{ID: -5, Name: "Parent"},
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	ctx := context.Background()
+
+	codecs := map[string]cache.Codec{
+		"JSON":   cache.JSONCodec{},
+		"Gob":    cache.GobCodec{},
+		"Binary": cache.BinaryCodec{},
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			c, err := cache.New(&cache.Config{
+				Backend: cache.BackendMemory,
+				Codec:   codec,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+			defer c.Close()
+
+			record := newCodecRecord()
+			records := cache.NewTyped[codecRecord](c)
+
+			if err := records.Set(ctx, "record", record); err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+
+			// Stored values are encoded with a header byte
+			raw, _ := c.GetStore().Get(ctx, "record")
+			data, ok := raw.([]byte)
+			if !ok || len(data) == 0 || data[0] != codec.ID() {
+				t.Errorf("Expected encoded value with header 0x%02x, got %T", codec.ID(), raw)
+			}
+
+			retrieved, err := records.Get(ctx, "record")
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+
+			if !retrieved.Created.Equal(record.Created) {
+				t.Errorf("Expected created %v, got %v", record.Created, retrieved.Created)
+			}
+			_, offset := retrieved.Created.Zone()
+			if offset != 3*60*60 {
+				t.Errorf("Expected zone offset to survive, got %d", offset)
+			}
+			// Zone names are not preserved, only offsets
+			retrieved.Created = record.Created
+			if !reflect.DeepEqual(retrieved, record) {
+				t.Errorf("Expected %+v, got %+v", record, retrieved)
+			}
+
+			var into codecRecord
+			if err := c.GetInto(ctx, "record", &into); err != nil {
+				t.Errorf("GetInto failed: %v", err)
+			}
+			if into.ID != record.ID {
+				t.Errorf("Expected ID %d, got %d", record.ID, into.ID)
+			}
+		})
+	}
+}
+
+func TestRawValuesWithoutCodec(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	// Raw bytes starting with header bytes must not be decoded on read
+	values := map[string][]byte{
+		"json":   {0x81, 0x01, 0x02},
+		"binary": {0x83, 0x01},
+		"escape": {0x80, 0x80},
+	}
+	for key, data := range values {
+		c.Set(ctx, key, data)
+
+		value, err := c.Get(ctx, key)
+		if err != nil {
+			t.Errorf("Get %s failed: %v", key, err)
+			continue
+		}
+		if got, ok := value.([]byte); !ok || !bytes.Equal(got, data) {
+			t.Errorf("Expected %s to round-trip as %v, got %T(%v)", key, data, value, value)
+		}
+	}
+
+	c.Set(ctx, "string", "\x81not json")
+	if value, err := c.Get(ctx, "string"); err != nil || value != "\x81not json" {
+		t.Errorf("Expected raw string to round-trip, got %q (%v)", value, err)
+	}
+}
+
+func TestGobCodecRequiresDest(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig().WithCodec(cache.GobCodec{}))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	fetch := func() (interface{}, error) { return newCodecRecord(), nil }
+	if _, err := c.GetOrSet(ctx, "record", fetch, time.Minute); err != nil {
+		t.Fatalf("GetOrSet miss failed: %v", err)
+	}
+	if _, err := c.GetOrSet(ctx, "record", fetch, time.Minute); !errors.Is(err, cache.ErrCodecRequiresDest) {
+		t.Errorf("Expected ErrCodecRequiresDest on a GetOrSet hit, got %v", err)
+	}
+	if _, err := c.GetMany(ctx, []string{"record"}); !errors.Is(err, cache.ErrCodecRequiresDest) {
+		t.Errorf("Expected ErrCodecRequiresDest from GetMany, got %v", err)
+	}
+
+	// Typed reads know the destination
+	record, err := cache.NewTyped[codecRecord](c).GetOrSet(ctx, "record", func(context.Context) (codecRecord, error) {
+		return codecRecord{}, nil
+	}, time.Minute)
+	if err != nil || record.Name != "John" {
+		t.Errorf("Expected typed GetOrSet hit to decode, got %+v (%v)", record, err)
+	}
+}
+
+func TestBinaryCodecGeneric(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(&cache.Config{
+		Backend: cache.BackendMemory,
+		Codec:   cache.BinaryCodec{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	c.Set(ctx, "number", 42)
+	c.Set(ctx, "list", []interface{}{"a", 1.5, true, nil})
+
+	// Integers stay integers instead of becoming float64
+	value, err := c.Get(ctx, "number")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if value != int64(42) {
+		t.Errorf("Expected int64(42), got %T(%v)", value, value)
+	}
+
+	value, err = c.Get(ctx, "list")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	expected := []interface{}{"a", 1.5, true, nil}
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Expected %v, got %v", expected, value)
+	}
+
+	// Counters are never encoded
+	c.Increment(ctx, "counter", 3)
+	value, _ = c.Get(ctx, "counter")
+	if value != int64(3) {
+		t.Errorf("Expected int64(3), got %T(%v)", value, value)
+	}
+
+	// Truncated data is rejected
+	var out interface{}
+	if err := (cache.BinaryCodec{}).Unmarshal([]byte{0xdb, 0x00}, &out); err == nil {
+		t.Error("Expected error for truncated data")
+	}
+}
@@ -0,0 +1,325 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Compression selects the algorithm used by CompressedStore
+type Compression string
+
+const (
+	// CompressionNone stores values as-is
+	CompressionNone Compression = ""
+
+	// CompressionGzip compresses values with gzip
+	CompressionGzip Compression = "gzip"
+
+	// CompressionFlate compresses values with raw DEFLATE (smaller framing than gzip)
+	CompressionFlate Compression = "flate"
+)
+
+// defaultCompressionThreshold is the smallest value compressed when no threshold is set
+const defaultCompressionThreshold = 1024
+
+// Header bytes marking compressed payloads, followed by a payload kind byte
+const (
+	compressedHeaderGzip  byte = 0x8e
+	compressedHeaderFlate byte = 0x8f
+)
+
+// Payload kinds recorded by store wrappers so values come back with their original type
+const (
+	payloadKindBytes  byte = 0
+	payloadKindString byte = 1
+)
+
+// payloadBytes returns the bytes of a value and whether it was a string.
+// Values other than strings and byte slices are JSON encoded and read back
+// as strings, just like RedisStore does.
+func payloadBytes(value interface{}) ([]byte, byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, payloadKindBytes, nil
+	case string:
+		return []byte(v), payloadKindString, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, payloadKindString, nil
+}
+
+// payloadValue converts bytes back to the kind recorded by payloadBytes
+func payloadValue(data []byte, kind byte) interface{} {
+	if kind == payloadKindString {
+		return string(data)
+	}
+	return data
+}
+
+// CompressionStats reports how much a CompressedStore saved
+type CompressionStats struct {
+	// Compressed is the number of values stored compressed
+	Compressed uint64
+
+	// Skipped is the number of values stored as-is because they were below
+	// the threshold or didn't shrink
+	Skipped uint64
+
+	// BytesIn is the total size of compressed values before compression
+	BytesIn uint64
+
+	// BytesOut is the total size of compressed values after compression
+	BytesOut uint64
+}
+
+// Ratio returns compressed size divided by original size (lower is better)
+func (s CompressionStats) Ratio() float64 {
+	if s.BytesIn == 0 {
+		return 1
+	}
+	return float64(s.BytesOut) / float64(s.BytesIn)
+}
+
+// CompressedStore compresses large values before passing them to another store.
+// Compressed payloads carry a header byte, so stores holding a mix of
+// compressed and uncompressed values are read back correctly.
+type CompressedStore struct {
+	next      Store
+	algorithm Compression
+	header    byte
+	threshold int
+	writers   sync.Pool
+
+	compressed uint64
+	skipped    uint64
+	bytesIn    uint64
+	bytesOut   uint64
+}
+
+// NewCompressedStore wraps next, compressing values of at least threshold bytes
+func NewCompressedStore(next Store, algorithm Compression, threshold int) (*CompressedStore, error) {
+	if threshold <= 0 {
+		threshold = defaultCompressionThreshold
+	}
+
+	store := &CompressedStore{
+		next:      next,
+		algorithm: algorithm,
+		threshold: threshold,
+	}
+
+	switch algorithm {
+	case CompressionGzip:
+		store.header = compressedHeaderGzip
+		store.writers.New = func() interface{} { return gzip.NewWriter(nil) }
+	case CompressionFlate:
+		store.header = compressedHeaderFlate
+		store.writers.New = func() interface{} {
+			w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+			return w
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression: %q", algorithm)
+	}
+
+	return store, nil
+}
+
+// compressWriter is implemented by both gzip and flate writers
+type compressWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// compress returns the compressed payload, including header and kind bytes
+func (s *CompressedStore) compress(data []byte, kind byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(s.header)
+	buf.WriteByte(kind)
+
+	w := s.writers.Get().(compressWriter)
+	defer s.writers.Put(w)
+
+	w.Reset(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decompress restores a payload written by compress
+func decompress(data []byte) (interface{}, error) {
+	var r io.ReadCloser
+	var err error
+
+	payload := bytes.NewReader(data[2:])
+	switch data[0] {
+	case compressedHeaderGzip:
+		r, err = gzip.NewReader(payload)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		r = flate.NewReader(payload)
+	}
+	defer r.Close()
+
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cache: corrupt compressed value: %w", err)
+	}
+
+	return payloadValue(decompressed, data[1]), nil
+}
+
+// isCompressed reports whether data carries a compression header
+func isCompressed(data []byte) bool {
+	return len(data) >= 2 &&
+		(data[0] == compressedHeaderGzip || data[0] == compressedHeaderFlate)
+}
+
+// Get retrieves a value, decompressing it if needed
+func (s *CompressedStore) Get(ctx context.Context, key string) (interface{}, error) {
+	value, err := s.next.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, ok := asBytes(value); ok && isCompressed(data) {
+		return decompress(data)
+	}
+	return unescapeRaw(value), nil
+}
+
+// Set stores a value, compressing it if it is at least the threshold size
+func (s *CompressedStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	stored, err := s.pack(value)
+	if err != nil {
+		return err
+	}
+	return s.next.Set(ctx, key, stored, ttl)
+}
+
+// SetWithTags stores a tagged value, compressing it if it is at least the threshold size
+func (s *CompressedStore) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	tagged, ok := s.next.(TagStore)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+
+	stored, err := s.pack(value)
+	if err != nil {
+		return err
+	}
+	return tagged.SetWithTags(ctx, key, stored, ttl, tags)
+}
+
+// InvalidateTags removes all keys associated with any of the tags
+func (s *CompressedStore) InvalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	tagged, ok := s.next.(TagStore)
+	if !ok {
+		return nil, ErrTagsNotSupported
+	}
+	return tagged.InvalidateTags(ctx, tags...)
+}
+
+// pack returns the value to store, compressed if that is worthwhile.
+// Values stored as-is are escaped if they start with a header byte, so they
+// are never mistaken for compressed payloads.
+func (s *CompressedStore) pack(value interface{}) (interface{}, error) {
+	data, kind, err := payloadBytes(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < s.threshold {
+		atomic.AddUint64(&s.skipped, 1)
+		return escapeRaw(value), nil
+	}
+
+	compressed, err := s.compress(data, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(compressed) >= len(data) {
+		// Incompressible data - not worth the decompression cost
+		atomic.AddUint64(&s.skipped, 1)
+		return escapeRaw(value), nil
+	}
+
+	atomic.AddUint64(&s.compressed, 1)
+	atomic.AddUint64(&s.bytesIn, uint64(len(data)))
+	atomic.AddUint64(&s.bytesOut, uint64(len(compressed)))
+	return compressed, nil
+}
+
+// Delete removes a value
+func (s *CompressedStore) Delete(ctx context.Context, key string) error {
+	return s.next.Delete(ctx, key)
+}
+
+// DeletePrefix removes all keys starting with prefix
+func (s *CompressedStore) DeletePrefix(ctx context.Context, prefix string) error {
+	deleter, ok := s.next.(PrefixDeleter)
+	if !ok {
+		return ErrPrefixNotSupported
+	}
+	return deleter.DeletePrefix(ctx, prefix)
+}
+
+// Has checks if a key exists
+func (s *CompressedStore) Has(ctx context.Context, key string) bool {
+	return s.next.Has(ctx, key)
+}
+
+// Increment increments a numeric value; counters are never compressed
+func (s *CompressedStore) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	return s.next.Increment(ctx, key, delta)
+}
+
+// Decrement decrements a numeric value; counters are never compressed
+func (s *CompressedStore) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	return s.next.Decrement(ctx, key, delta)
+}
+
+// Clear removes all entries
+func (s *CompressedStore) Clear(ctx context.Context) error {
+	return s.next.Clear(ctx)
+}
+
+// Close closes the wrapped store
+func (s *CompressedStore) Close() error {
+	return s.next.Close()
+}
+
+// Stats returns compression counters
+func (s *CompressedStore) Stats() CompressionStats {
+	return CompressionStats{
+		Compressed: atomic.LoadUint64(&s.compressed),
+		Skipped:    atomic.LoadUint64(&s.skipped),
+		BytesIn:    atomic.LoadUint64(&s.bytesIn),
+		BytesOut:   atomic.LoadUint64(&s.bytesOut),
+	}
+}
+
+// Unwrap returns the wrapped store
+func (s *CompressedStore) Unwrap() Store {
+	return s.next
+}
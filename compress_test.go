@@ -0,0 +1,118 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+func TestCompressedStore(t *testing.T) {
+	ctx := context.Background()
+
+	for _, algorithm := range []cache.Compression{cache.CompressionGzip, cache.CompressionFlate} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			backend := cache.NewMemoryStore(time.Minute)
+			store, err := cache.NewCompressedStore(backend, algorithm, 100)
+			if err != nil {
+				t.Fatalf("Failed to create store: %v", err)
+			}
+			defer store.Close()
+
+			large := strings.Repeat("compressible ", 100)
+			store.Set(ctx, "large", large, 0)
+			store.Set(ctx, "small", "tiny", 0)
+			store.Set(ctx, "bytes", []byte(large), 0)
+
+			// Large values are compressed in the backend
+			raw, _ := backend.Get(ctx, "large")
+			if data, ok := raw.([]byte); !ok || len(data) >= len(large) {
+				t.Errorf("Expected compressed bytes, got %T", raw)
+			}
+
+			// Small values are left alone
+			raw, _ = backend.Get(ctx, "small")
+			if raw != "tiny" {
+				t.Errorf("Expected tiny, got %v", raw)
+			}
+
+			// Values come back with their original type
+			value, err := store.Get(ctx, "large")
+			if err != nil {
+				t.Errorf("Get failed: %v", err)
+			}
+			if value != large {
+				t.Error("Large string did not round trip")
+			}
+
+			value, _ = store.Get(ctx, "bytes")
+			if data, ok := value.([]byte); !ok || string(data) != large {
+				t.Errorf("Expected []byte, got %T", value)
+			}
+
+			stats := store.Stats()
+			if stats.Compressed != 2 || stats.Skipped != 1 {
+				t.Errorf("Expected 2 compressed and 1 skipped, got %+v", stats)
+			}
+			if stats.Ratio() >= 0.5 {
+				t.Errorf("Expected ratio below 0.5, got %f", stats.Ratio())
+			}
+		})
+	}
+}
+
+func TestCompressedStoreRawHeaders(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := cache.NewCompressedStore(cache.NewMemoryStore(time.Minute), cache.CompressionGzip, 100)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	// Small values starting with header bytes are stored as-is and must not
+	// be mistaken for compressed payloads
+	for _, data := range [][]byte{{0x8e, 0x00, 0x01}, {0x8f, 0x01}, {0x80}} {
+		store.Set(ctx, "raw", data, 0)
+
+		value, err := store.Get(ctx, "raw")
+		if err != nil {
+			t.Fatalf("Get failed for %v: %v", data, err)
+		}
+		if got, ok := value.([]byte); !ok || !bytes.Equal(got, data) {
+			t.Errorf("Expected %v to round-trip, got %T(%v)", data, value, value)
+		}
+	}
+}
+
+func TestCompressedJSON(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(&cache.Config{
+		Backend:              cache.BackendMemory,
+		Compression:          cache.CompressionGzip,
+		CompressionThreshold: 64,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	type Report struct {
+		Lines []string `json:"lines"`
+	}
+
+	report := Report{Lines: strings.Split(strings.Repeat("line,", 50), ",")}
+	c.SetJSON(ctx, "report", report)
+
+	var retrieved Report
+	if err := c.GetJSON(ctx, "report", &retrieved); err != nil {
+		t.Fatalf("GetJSON failed: %v", err)
+	}
+	if len(retrieved.Lines) != len(report.Lines) {
+		t.Errorf("Expected %d lines, got %d", len(report.Lines), len(retrieved.Lines))
+	}
+}
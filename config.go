@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"crypto/tls"
 	"time"
 )
 
@@ -13,6 +14,9 @@ const (
 	
 	// BackendRedis uses Redis storage (distributed)
 	BackendRedis Backend = "redis"
+
+	// BackendTiered serves reads from a bounded local memory tier backed by Redis
+	BackendTiered Backend = "tiered"
 )
 
 // Config holds the cache configuration
@@ -21,18 +25,191 @@ type Config struct {
 	// Default: BackendMemory
 	Backend Backend
 
-	// RedisURL is the Redis connection URL
+	// RedisURL is the Redis connection URL. With RedisAddrs it only supplies
+	// credentials, database and TLS settings
 	// Format: redis://[:password@]host[:port][/db]
-	// Required if Backend is BackendRedis
+	// Required if Backend is BackendRedis or BackendTiered, unless RedisAddrs is set
 	RedisURL string
 
+	// RedisAddrs lists the nodes of a Redis Cluster or the Sentinels of a
+	// Sentinel setup. Several addresses select Redis Cluster unless
+	// RedisMasterName is set
+	// Default: nil (single node at RedisURL)
+	RedisAddrs []string
+
+	// RedisMasterName is the name of the master monitored by the Sentinels in RedisAddrs
+	// Default: "" (no Sentinel)
+	RedisMasterName string
+
+	// RedisCluster selects Redis Cluster even if RedisAddrs (or RedisURL) holds a single seed node
+	// Default: false
+	RedisCluster bool
+
+	// Redis tunes the Redis connection. Fields that are set override those
+	// parsed from RedisURL
+	Redis RedisOptions
+
+	// CircuitBreakerThreshold opens a circuit breaker around Redis after this
+	// many consecutive failures. While it is open, calls fail fast with
+	// ErrRedisUnavailable and Redis is probed in the background (Redis and tiered backends)
+	// Default: 0 (disabled)
+	CircuitBreakerThreshold int
+
+	// CircuitBreakerTimeout is how long the circuit stays open between probes
+	// Default: 5 seconds
+	CircuitBreakerTimeout time.Duration
+
+	// CircuitBreakerFallback serves calls from a local memory store, bounded
+	// like the local tier, while the circuit is open
+	// Default: false
+	CircuitBreakerFallback bool
+
 	// DefaultTTL is the default expiration time for cache entries
 	// Default: 1 hour
 	DefaultTTL time.Duration
 
-	// CleanupInterval is how often to clean expired entries (memory backend only)
+	// CleanupInterval is how often to clean expired entries (memory backend and local tier)
 	// Default: 10 minutes
 	CleanupInterval time.Duration
+
+	// CleanupBudget caps how many expired entries are reclaimed per cleanup tick (memory backend and local tier)
+	// Default: 0 (no cap)
+	CleanupBudget int
+
+	// Shards splits the memory backend into independently locked shards
+	// Default: 0 (a single MemoryStore)
+	Shards int
+
+	// MaxEntries bounds the number of entries kept in memory (memory backend and local tier)
+	// Default: 0 (unbounded; 10000 for the local tier)
+	MaxEntries int
+
+	// MaxBytes bounds the approximate total size of cached entries (memory backend and local tier).
+	// Single entries larger than this are rejected with ErrItemTooLarge.
+	// Default: 0 (unbounded)
+	MaxBytes int64
+
+	// MaxItemBytes rejects single entries larger than this with ErrItemTooLarge (memory backend and local tier)
+	// Default: 0 (no limit)
+	MaxItemBytes int64
+
+	// Sizer estimates the size of values for MaxBytes and MaxItemBytes
+	// Default: DefaultSizer
+	Sizer Sizer
+
+	// EvictionPolicy creates the policy used to evict entries once MaxEntries or MaxBytes is reached
+	// Default: NewLRUPolicy
+	EvictionPolicy func() EvictionPolicy
+
+	// L1TTL caps how long values stay in the local tier (tiered backend only)
+	// Default: 1 minute
+	L1TTL time.Duration
+
+	// InvalidationChannel is the Redis pub/sub channel used to drop stale local
+	// copies on other instances after writes (tiered backend only)
+	// Default: "" (disabled)
+	InvalidationChannel string
+
+	// Codec serializes values before they are stored, for both backends
+	// Default: nil (memory keeps Go values, Redis stores strings and JSON)
+	Codec Codec
+
+	// Compression compresses large values before they are stored.
+	// Compressed values other than strings and byte slices are read back as JSON strings
+	// Default: CompressionNone
+	Compression Compression
+
+	// CompressionThreshold is the smallest value size, in bytes, that gets compressed
+	// Default: 1024
+	CompressionThreshold int
+
+	// EncryptionKeys enables AES-GCM encryption of stored values, keyed by key ID.
+	// Keys must be 16, 24 or 32 bytes; keep retired keys here so old entries stay readable
+	// Default: nil (disabled)
+	EncryptionKeys map[string][]byte
+
+	// EncryptionKeyID selects the key from EncryptionKeys used to encrypt new values
+	// Required if EncryptionKeys is set
+	EncryptionKeyID string
+
+	// AllowFlush lets Clear on the root cache flush the whole Redis database,
+	// including keys written by other services. Namespaced caches can always be cleared
+	// Default: false (Clear fails with ErrFlushDisabled on Redis and tiered backends)
+	AllowFlush bool
+
+	// StampedeLockTTL enables a short Redis lock around GetOrSet fetches so that
+	// only one instance runs the fetcher for a missed key (Redis and tiered backends)
+	// Default: 0 (disabled, misses are only coalesced within the process)
+	StampedeLockTTL time.Duration
+
+	// RefreshWorkers bounds how many refresh-ahead loaders run at once
+	// Default: 4
+	RefreshWorkers int
+
+	// NegativeTTL is how long GetOrSet remembers that a key does not exist
+	// after its fetcher returned ErrNotExist
+	// Default: 1 minute
+	NegativeTTL time.Duration
+}
+
+// RedisOptions tunes the Redis connection; zero fields keep the values from
+// RedisURL or the go-redis defaults
+type RedisOptions struct {
+	// Username and Password authenticate with Redis ACLs
+	Username string
+	Password string
+
+	// SentinelUsername and SentinelPassword authenticate with the Sentinels
+	// when they use different credentials than the master
+	SentinelUsername string
+	SentinelPassword string
+
+	// ClientName is sent with CLIENT SETNAME on every connection
+	ClientName string
+
+	// PoolSize is the maximum number of connections per node
+	// Default: 10 per CPU
+	PoolSize int
+
+	// MinIdleConns keeps this many idle connections open per node
+	MinIdleConns int
+
+	// PoolTimeout is how long a command waits for a free connection
+	// Default: ReadTimeout + 1 second
+	PoolTimeout time.Duration
+
+	// MaxRetries is how many times a failed command is retried; -1 disables retries
+	// Default: 3
+	MaxRetries int
+
+	// DialTimeout bounds establishing new connections
+	// Default: 5 seconds
+	DialTimeout time.Duration
+
+	// ReadTimeout and WriteTimeout bound socket reads and writes; -1 disables them
+	// Default: 3 seconds
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// TLSConfig enables TLS, replacing the configuration implied by a rediss:// URL
+	TLSConfig *tls.Config
+
+	// TLSCertFile and TLSKeyFile hold a PEM client certificate and key for
+	// mutual TLS. Setting any TLS file enables TLS
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TLSCAFile holds PEM certificates trusted instead of the system roots
+	TLSCAFile string
+
+	// PingTimeout bounds the connection test done when the cache is created
+	// Default: 5 seconds
+	PingTimeout time.Duration
+
+	// LazyConnect skips the connection test, so New succeeds while Redis is
+	// down and errors surface on the first command instead
+	// Default: false
+	LazyConnect bool
 }
 
 // DefaultConfig returns a Config with sensible defaults
@@ -56,8 +233,129 @@ func (c *Config) WithRedisURL(url string) *Config {
 	return c
 }
 
+// WithRedisCluster connects to a Redis Cluster through the given seed nodes
+func (c *Config) WithRedisCluster(addrs ...string) *Config {
+	c.RedisAddrs = addrs
+	c.RedisCluster = true
+	return c
+}
+
+// WithRedisSentinel connects to the master named masterName through the given Sentinels
+func (c *Config) WithRedisSentinel(masterName string, addrs ...string) *Config {
+	c.RedisAddrs = addrs
+	c.RedisMasterName = masterName
+	c.RedisCluster = false
+	return c
+}
+
 // WithDefaultTTL sets the default TTL
 func (c *Config) WithDefaultTTL(ttl time.Duration) *Config {
 	c.DefaultTTL = ttl
 	return c
 }
+
+// WithShards splits the memory backend into n independently locked shards
+func (c *Config) WithShards(n int) *Config {
+	c.Shards = n
+	return c
+}
+
+// WithMaxEntries bounds the memory backend to max entries
+func (c *Config) WithMaxEntries(max int) *Config {
+	c.MaxEntries = max
+	return c
+}
+
+// WithMaxBytes bounds the memory backend to an approximate byte budget
+func (c *Config) WithMaxBytes(max int64) *Config {
+	c.MaxBytes = max
+	return c
+}
+
+// WithMaxItemBytes rejects single entries larger than max bytes
+func (c *Config) WithMaxItemBytes(max int64) *Config {
+	c.MaxItemBytes = max
+	return c
+}
+
+// WithEvictionPolicy sets the eviction policy used by a bounded memory backend
+func (c *Config) WithEvictionPolicy(policy func() EvictionPolicy) *Config {
+	c.EvictionPolicy = policy
+	return c
+}
+
+// WithL1TTL caps how long values stay in the local tier of a tiered backend
+func (c *Config) WithL1TTL(ttl time.Duration) *Config {
+	c.L1TTL = ttl
+	return c
+}
+
+// WithInvalidationChannel keeps local tiers coherent across instances via Redis pub/sub
+func (c *Config) WithInvalidationChannel(channel string) *Config {
+	c.InvalidationChannel = channel
+	return c
+}
+
+// WithCodec serializes values with the given codec
+func (c *Config) WithCodec(codec Codec) *Config {
+	c.Codec = codec
+	return c
+}
+
+// WithCompression compresses values of at least threshold bytes
+func (c *Config) WithCompression(algorithm Compression, threshold int) *Config {
+	c.Compression = algorithm
+	c.CompressionThreshold = threshold
+	return c
+}
+
+// WithEncryption encrypts values with the key named activeKeyID while
+// keeping the other keys available for reading older entries
+func (c *Config) WithEncryption(keys map[string][]byte, activeKeyID string) *Config {
+	c.EncryptionKeys = keys
+	c.EncryptionKeyID = activeKeyID
+	return c
+}
+
+// WithAllowFlush lets Clear flush the whole Redis database
+func (c *Config) WithAllowFlush() *Config {
+	c.AllowFlush = true
+	return c
+}
+
+// WithStampedeLock coordinates GetOrSet fetches across instances using a Redis lock
+func (c *Config) WithStampedeLock(ttl time.Duration) *Config {
+	c.StampedeLockTTL = ttl
+	return c
+}
+
+// WithRefreshWorkers bounds how many refresh-ahead loaders run at once
+func (c *Config) WithRefreshWorkers(n int) *Config {
+	c.RefreshWorkers = n
+	return c
+}
+
+// WithRedisOptions tunes the Redis connection
+func (c *Config) WithRedisOptions(opts RedisOptions) *Config {
+	c.Redis = opts
+	return c
+}
+
+// WithCircuitBreaker fails Redis calls fast for timeout after threshold consecutive failures
+func (c *Config) WithCircuitBreaker(threshold int, timeout time.Duration) *Config {
+	c.CircuitBreakerThreshold = threshold
+	c.CircuitBreakerTimeout = timeout
+	return c
+}
+
+// WithCircuitBreakerFallback serves calls from a local memory store while the circuit is open
+func (c *Config) WithCircuitBreakerFallback() *Config {
+	c.CircuitBreakerFallback = true
+	return c
+}
+
+// WithNegativeTTL sets how long not-found results from GetOrSet fetchers are cached
+func (c *Config) WithNegativeTTL(ttl time.Duration) *Config {
+	c.NegativeTTL = ttl
+	return c
+}
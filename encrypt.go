@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// encryptedHeader marks encrypted payloads
+const encryptedHeader byte = 0x8d
+
+var (
+	// ErrUnknownKeyID is returned when a value was encrypted with a key that is not configured
+	ErrUnknownKeyID = errors.New("unknown encryption key ID")
+
+	// ErrNotEncrypted is returned for stored values that should be encrypted but
+	// are not, such as plaintext written to Redis by another client
+	ErrNotEncrypted = errors.New("value is not encrypted")
+)
+
+// DecryptionError is returned when an encrypted value fails authentication,
+// meaning it was tampered with, corrupted, or encrypted with a different key
+type DecryptionError struct {
+	Key   string
+	KeyID string
+	Err   error
+}
+
+// Error implements the error interface
+func (e *DecryptionError) Error() string {
+	return fmt.Sprintf("cache: failed to decrypt value for key %q with key ID %q: %v", e.Key, e.KeyID, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *DecryptionError) Unwrap() error {
+	return e.Err
+}
+
+// EncryptedStore encrypts values with AES-GCM before passing them to another store.
+// Each value records the ID of the key that encrypted it, so keys can be
+// rotated by adding a new active key while old entries remain readable.
+// Values other than strings and byte slices are encrypted as JSON and read
+// back as strings. Counters are stored in plaintext.
+type EncryptedStore struct {
+	next     Store
+	aeads    map[string]cipher.AEAD
+	activeID string
+}
+
+// NewEncryptedStore wraps next, encrypting new values with the key named activeKeyID.
+// Keys must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewEncryptedStore(next Store, keys map[string][]byte, activeKeyID string) (*EncryptedStore, error) {
+	if _, found := keys[activeKeyID]; !found {
+		return nil, fmt.Errorf("active encryption key %q is not configured", activeKeyID)
+	}
+
+	store := &EncryptedStore{
+		next:     next,
+		aeads:    make(map[string]cipher.AEAD, len(keys)),
+		activeID: activeKeyID,
+	}
+
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return nil, fmt.Errorf("encryption key ID %q must be 1 to 255 bytes", id)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		store.aeads[id] = aead
+	}
+
+	return store, nil
+}
+
+// encrypt seals a value as header | kind | id length | id | nonce | ciphertext.
+// The header and the cache key are authenticated, so an encrypted value
+// can't be moved to another key unnoticed.
+func (s *EncryptedStore) encrypt(key string, value interface{}) ([]byte, error) {
+	data, kind, err := payloadBytes(value)
+	if err != nil {
+		return nil, err
+	}
+
+	aead := s.aeads[s.activeID]
+	prefix := make([]byte, 0, 3+len(s.activeID)+aead.NonceSize())
+	prefix = append(prefix, encryptedHeader, kind, byte(len(s.activeID)))
+	prefix = append(prefix, s.activeID...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	additional := append(prefix[:len(prefix):len(prefix)], key...)
+	sealed := aead.Seal(append(prefix, nonce...), nonce, data, additional)
+	return sealed, nil
+}
+
+// decrypt opens a value sealed by encrypt
+func (s *EncryptedStore) decrypt(key string, data []byte) (interface{}, error) {
+	if len(data) < 3 || len(data) < 3+int(data[2]) {
+		return nil, &DecryptionError{Key: key, Err: errors.New("truncated value")}
+	}
+
+	kind := data[1]
+	idEnd := 3 + int(data[2])
+	keyID := string(data[3:idEnd])
+
+	aead, found := s.aeads[keyID]
+	if !found {
+		return nil, &DecryptionError{Key: key, KeyID: keyID, Err: ErrUnknownKeyID}
+	}
+
+	if len(data) < idEnd+aead.NonceSize() {
+		return nil, &DecryptionError{Key: key, KeyID: keyID, Err: errors.New("truncated value")}
+	}
+	nonce := data[idEnd : idEnd+aead.NonceSize()]
+
+	additional := append(data[:idEnd:idEnd], key...)
+	plaintext, err := aead.Open(nil, nonce, data[idEnd+aead.NonceSize():], additional)
+	if err != nil {
+		return nil, &DecryptionError{Key: key, KeyID: keyID, Err: err}
+	}
+
+	return payloadValue(plaintext, kind), nil
+}
+
+// Get retrieves and decrypts a value. Counters, the only values stored
+// without encryption, are returned as-is; any other unencrypted value fails
+// with a DecryptionError wrapping ErrNotEncrypted.
+func (s *EncryptedStore) Get(ctx context.Context, key string) (interface{}, error) {
+	value, err := s.next.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := asBytes(value)
+	if ok && len(data) > 0 && data[0] == encryptedHeader {
+		return s.decrypt(key, data)
+	}
+	if isCounter(value) {
+		return value, nil
+	}
+	return nil, &DecryptionError{Key: key, Err: ErrNotEncrypted}
+}
+
+// isCounter reports whether value is an integer as written by Increment:
+// an int64 in memory, or its decimal form in Redis
+func isCounter(value interface{}) bool {
+	switch v := value.(type) {
+	case int64:
+		return true
+	case string:
+		_, err := strconv.ParseInt(v, 10, 64)
+		return err == nil
+	case []byte:
+		_, err := strconv.ParseInt(string(v), 10, 64)
+		return err == nil
+	}
+	return false
+}
+
+// Set encrypts and stores a value
+func (s *EncryptedStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	sealed, err := s.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return s.next.Set(ctx, key, sealed, ttl)
+}
+
+// SetWithTags encrypts and stores a tagged value
+func (s *EncryptedStore) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	tagged, ok := s.next.(TagStore)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+
+	sealed, err := s.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return tagged.SetWithTags(ctx, key, sealed, ttl, tags)
+}
+
+// InvalidateTags removes all keys associated with any of the tags
+func (s *EncryptedStore) InvalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	tagged, ok := s.next.(TagStore)
+	if !ok {
+		return nil, ErrTagsNotSupported
+	}
+	return tagged.InvalidateTags(ctx, tags...)
+}
+
+// Delete removes a value
+func (s *EncryptedStore) Delete(ctx context.Context, key string) error {
+	return s.next.Delete(ctx, key)
+}
+
+// DeletePrefix removes all keys starting with prefix
+func (s *EncryptedStore) DeletePrefix(ctx context.Context, prefix string) error {
+	deleter, ok := s.next.(PrefixDeleter)
+	if !ok {
+		return ErrPrefixNotSupported
+	}
+	return deleter.DeletePrefix(ctx, prefix)
+}
+
+// Has checks if a key exists
+func (s *EncryptedStore) Has(ctx context.Context, key string) bool {
+	return s.next.Has(ctx, key)
+}
+
+// Increment increments a numeric value; counters are not encrypted
+func (s *EncryptedStore) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	return s.next.Increment(ctx, key, delta)
+}
+
+// Decrement decrements a numeric value; counters are not encrypted
+func (s *EncryptedStore) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	return s.next.Decrement(ctx, key, delta)
+}
+
+// Clear removes all entries
+func (s *EncryptedStore) Clear(ctx context.Context) error {
+	return s.next.Clear(ctx)
+}
+
+// Close closes the wrapped store
+func (s *EncryptedStore) Close() error {
+	return s.next.Close()
+}
+
+// Unwrap returns the wrapped store
+func (s *EncryptedStore) Unwrap() Store {
+	return s.next
+}
@@ -0,0 +1,147 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	ctx := context.Background()
+
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	backend := cache.NewMemoryStore(time.Minute)
+	defer backend.Close()
+
+	old, err := cache.NewEncryptedStore(backend, map[string][]byte{"v1": oldKey}, "v1")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	old.Set(ctx, "profile", "secret data", 0)
+	old.Set(ctx, "raw", []byte{0x00, 0xff}, 0)
+
+	// Values are not stored in plaintext
+	raw, _ := backend.Get(ctx, "profile")
+	if data, ok := raw.([]byte); !ok || bytes.Contains(data, []byte("secret")) {
+		t.Errorf("Expected ciphertext, got %T", raw)
+	}
+
+	// After rotation, old entries remain readable and new ones use the new key
+	rotated, err := cache.NewEncryptedStore(backend, map[string][]byte{"v1": oldKey, "v2": newKey}, "v2")
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	value, err := rotated.Get(ctx, "profile")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if value != "secret data" {
+		t.Errorf("Expected secret data, got %v", value)
+	}
+
+	value, _ = rotated.Get(ctx, "raw")
+	if data, ok := value.([]byte); !ok || !bytes.Equal(data, []byte{0x00, 0xff}) {
+		t.Errorf("Expected []byte, got %T(%v)", value, value)
+	}
+
+	rotated.Set(ctx, "session", "new data", 0)
+
+	// The old store doesn't know the new key
+	var decryptErr *cache.DecryptionError
+	_, err = old.Get(ctx, "session")
+	if !errors.As(err, &decryptErr) || !errors.Is(err, cache.ErrUnknownKeyID) {
+		t.Errorf("Expected unknown key error, got %v", err)
+	}
+
+	// Tampered values fail authentication
+	raw, _ = backend.Get(ctx, "session")
+	tampered := append([]byte(nil), raw.([]byte)...)
+	tampered[len(tampered)-1] ^= 1
+	backend.Set(ctx, "session", tampered, 0)
+
+	_, err = rotated.Get(ctx, "session")
+	if !errors.As(err, &decryptErr) || decryptErr.KeyID != "v2" {
+		t.Errorf("Expected DecryptionError for v2, got %v", err)
+	}
+
+	// Values can't be moved to another key
+	backend.Set(ctx, "moved", raw, 0)
+	if _, err := rotated.Get(ctx, "moved"); !errors.As(err, &decryptErr) {
+		t.Errorf("Expected DecryptionError for moved value, got %v", err)
+	}
+
+	if _, err := cache.NewEncryptedStore(backend, map[string][]byte{"v1": []byte("short")}, "v1"); err == nil {
+		t.Error("Expected error for invalid key length")
+	}
+}
+
+func TestEncryptedCachePlaintext(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig().
+		WithEncryption(map[string][]byte{"k1": bytes.Repeat([]byte{7}, 16)}, "k1"))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	// Plaintext planted below the encryption layer must not be trusted
+	backend := c.GetStore().(*cache.EncryptedStore).Unwrap()
+	backend.Set(ctx, "planted", "forged value", 0)
+	backend.Set(ctx, "planted-bytes", []byte{0x81, '1'}, 0)
+
+	var decryptErr *cache.DecryptionError
+	for _, key := range []string{"planted", "planted-bytes"} {
+		if _, err := c.Get(ctx, key); !errors.As(err, &decryptErr) || !errors.Is(err, cache.ErrNotEncrypted) {
+			t.Errorf("Expected ErrNotEncrypted for %s, got %v", key, err)
+		}
+	}
+
+	// Counters stay readable
+	c.Increment(ctx, "counter", 5)
+	if value, err := c.Get(ctx, "counter"); err != nil || value != int64(5) {
+		t.Errorf("Expected counter 5, got %v (%v)", value, err)
+	}
+}
+
+func TestEncryptedCache(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig().
+		WithEncryption(map[string][]byte{"k1": bytes.Repeat([]byte{7}, 16)}, "k1").
+		WithCompression(cache.CompressionGzip, 16))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	type User struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	user := User{Name: "John", Email: "john@example.com"}
+	c.SetJSON(ctx, "user", user)
+
+	var retrieved User
+	if err := c.GetJSON(ctx, "user", &retrieved); err != nil {
+		t.Fatalf("GetJSON failed: %v", err)
+	}
+	if retrieved != user {
+		t.Errorf("Expected %+v, got %+v", user, retrieved)
+	}
+
+	// Counters still work
+	c.Increment(ctx, "visits", 2)
+	if value, _ := c.Get(ctx, "visits"); value != int64(2) {
+		t.Errorf("Expected 2, got %v", value)
+	}
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Middleware wraps a Store to add behavior, such as logging or auditing,
+// around its operations
+type Middleware func(next Store) Store
+
+// Use wraps the store of the cache with middleware. Each middleware wraps
+// the ones added before it, so the last one added sees each call first.
+// Middleware sees values after codec encoding, and must implement and forward
+// TagStore and PrefixDeleter for tags and namespaced Clear to keep working.
+// Use is not safe for concurrent use with other operations: call it right
+// after New, before creating namespaces.
+func (c *Cache) Use(middleware ...Middleware) {
+	for _, wrap := range middleware {
+		c.store = wrap(c.store)
+	}
+}
+
+// Event callbacks. Keys include the namespace prefix, if any.
+type (
+	// HitFunc is called when a read finds a value
+	HitFunc func(ctx context.Context, key string)
+
+	// MissFunc is called when a read finds nothing or fails
+	MissFunc func(ctx context.Context, key string)
+
+	// SetFunc is called after a value was stored
+	SetFunc func(ctx context.Context, key string, value interface{}, ttl time.Duration)
+
+	// EvictFunc is called after the memory store evicted an entry to stay
+	// within its limits. The value is passed as stored, after encoding.
+	EvictFunc func(key string, value interface{})
+
+	// ExpireFunc is called after the memory store reclaimed an expired entry.
+	// The value is passed as stored, after encoding.
+	ExpireFunc func(key string, value interface{})
+
+	// ErrorFunc is called when an operation fails for any reason other than a missing key
+	ErrorFunc func(ctx context.Context, op Operation, key string, err error)
+
+	// CircuitFunc is called after the circuit breaker around Redis changed state
+	CircuitFunc func(from, to CircuitState)
+)
+
+// hookSet is an immutable set of callbacks
+type hookSet struct {
+	hit     []HitFunc
+	miss    []MissFunc
+	set     []SetFunc
+	evict   []EvictFunc
+	expire  []ExpireFunc
+	err     []ErrorFunc
+	circuit []CircuitFunc
+}
+
+// eventHooks holds registered callbacks. Registration copies the set, so
+// firing events never takes a lock.
+type eventHooks struct {
+	mu      sync.Mutex
+	current atomic.Value // *hookSet
+}
+
+// load returns the current callbacks
+func (e *eventHooks) load() *hookSet {
+	if hooks, ok := e.current.Load().(*hookSet); ok {
+		return hooks
+	}
+	return &hookSet{}
+}
+
+// update registers callbacks by applying fn to a copy of the current set
+func (e *eventHooks) update(fn func(hooks *hookSet)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	hooks := *e.load()
+	fn(&hooks)
+	e.current.Store(&hooks)
+}
+
+// with appends fn to a copy of fns, leaving sets already published untouched
+func with[T any](fns []T, fn T) []T {
+	return append(fns[:len(fns):len(fns)], fn)
+}
+
+// hit fires hit callbacks
+func (e *eventHooks) hit(ctx context.Context, key string) {
+	for _, fn := range e.load().hit {
+		fn(ctx, key)
+	}
+}
+
+// miss fires miss callbacks
+func (e *eventHooks) miss(ctx context.Context, key string) {
+	for _, fn := range e.load().miss {
+		fn(ctx, key)
+	}
+}
+
+// set fires set callbacks
+func (e *eventHooks) set(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	for _, fn := range e.load().set {
+		fn(ctx, key, value, ttl)
+	}
+}
+
+// evicted fires evict callbacks; it is passed to memory stores as MemoryOptions.OnEvict
+func (e *eventHooks) evicted(key string, value interface{}) {
+	for _, fn := range e.load().evict {
+		fn(key, value)
+	}
+}
+
+// expired fires expire callbacks; it is passed to memory stores as MemoryOptions.OnExpire
+func (e *eventHooks) expired(key string, value interface{}) {
+	for _, fn := range e.load().expire {
+		fn(key, value)
+	}
+}
+
+// failed fires error callbacks, ignoring missing keys
+func (e *eventHooks) failed(ctx context.Context, op Operation, key string, err error) {
+	if errors.Is(err, ErrNotFound) {
+		return
+	}
+	for _, fn := range e.load().err {
+		fn(ctx, op, key, err)
+	}
+}
+
+// circuitChanged fires circuit callbacks; it is passed to circuit breakers as
+// CircuitBreakerOptions.OnStateChange
+func (e *eventHooks) circuitChanged(from, to CircuitState) {
+	for _, fn := range e.load().circuit {
+		fn(from, to)
+	}
+}
+
+// OnHit registers a callback for reads that find a value
+func (c *Cache) OnHit(fn HitFunc) {
+	c.events.update(func(hooks *hookSet) { hooks.hit = with(hooks.hit, fn) })
+}
+
+// OnMiss registers a callback for reads that find nothing or fail
+func (c *Cache) OnMiss(fn MissFunc) {
+	c.events.update(func(hooks *hookSet) { hooks.miss = with(hooks.miss, fn) })
+}
+
+// OnSet registers a callback for successful writes. Writes made by
+// GetOrSetStale report the value and its hard TTL; the markers GetOrSet
+// stores for ErrNotExist results are not reported.
+func (c *Cache) OnSet(fn SetFunc) {
+	c.events.update(func(hooks *hookSet) { hooks.set = with(hooks.set, fn) })
+}
+
+// OnEvict registers a callback for entries evicted by the memory backend or
+// local tier. Callbacks run after the store lock is released. Redis
+// evictions are not reported.
+func (c *Cache) OnEvict(fn EvictFunc) {
+	c.events.update(func(hooks *hookSet) { hooks.evict = with(hooks.evict, fn) })
+}
+
+// OnExpire registers a callback for expired entries reclaimed by the memory
+// backend or local tier. Callbacks run after the store lock is released.
+// Redis expirations are not reported.
+func (c *Cache) OnExpire(fn ExpireFunc) {
+	c.events.update(func(hooks *hookSet) { hooks.expire = with(hooks.expire, fn) })
+}
+
+// OnError registers a callback for failed operations
+func (c *Cache) OnError(fn ErrorFunc) {
+	c.events.update(func(hooks *hookSet) { hooks.err = with(hooks.err, fn) })
+}
+
+// OnCircuitChange registers a callback for state changes of the circuit
+// breaker around Redis, enabled with CircuitBreakerThreshold
+func (c *Cache) OnCircuitChange(fn CircuitFunc) {
+	c.events.update(func(hooks *hookSet) { hooks.circuit = with(hooks.circuit, fn) })
+}
@@ -0,0 +1,155 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+// countingStore counts the calls that reach the wrapped store
+type countingStore struct {
+	cache.Store
+	name  string
+	calls *[]string
+}
+
+func (s *countingStore) Get(ctx context.Context, key string) (interface{}, error) {
+	*s.calls = append(*s.calls, s.name+":get:"+key)
+	return s.Store.Get(ctx, key)
+}
+
+// failingStore fails every write
+type failingStore struct {
+	cache.Store
+}
+
+func (s failingStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return errors.New("write failed")
+}
+
+func TestUse(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	var calls []string
+	c.Use(
+		func(next cache.Store) cache.Store { return &countingStore{Store: next, name: "inner", calls: &calls} },
+		func(next cache.Store) cache.Store { return &countingStore{Store: next, name: "outer", calls: &calls} },
+	)
+
+	c.Set(ctx, "key", "value")
+	if value, err := c.Get(ctx, "key"); err != nil || value != "value" {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	if len(calls) != 2 || calls[0] != "outer:get:key" || calls[1] != "inner:get:key" {
+		t.Errorf("Expected outer then inner middleware, got %v", calls)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(&cache.Config{
+		Backend:         cache.BackendMemory,
+		DefaultTTL:      time.Minute,
+		CleanupInterval: 10 * time.Millisecond,
+		MaxEntries:      1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	var mu sync.Mutex
+	events := make(map[string][]string)
+	record := func(event, key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events[event] = append(events[event], key)
+	}
+
+	c.OnHit(func(ctx context.Context, key string) { record("hit", key) })
+	c.OnMiss(func(ctx context.Context, key string) { record("miss", key) })
+	c.OnSet(func(ctx context.Context, key string, value interface{}, ttl time.Duration) { record("set", key) })
+	c.OnEvict(func(key string, value interface{}) { record("evict", key) })
+	c.OnExpire(func(key string, value interface{}) { record("expire", key) })
+	c.OnError(func(ctx context.Context, op cache.Operation, key string, err error) {
+		record("error", string(op)+":"+key)
+	})
+
+	users := c.Namespace("users")
+	users.Set(ctx, "a", "1")
+	users.Get(ctx, "a")
+	users.Get(ctx, "missing")
+
+	// MaxEntries is 1, so this evicts users:a and later expires
+	c.SetWithTTL(ctx, "b", "2", 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	c.GetOrSet(ctx, "failing", func() (interface{}, error) {
+		return nil, errors.New("boom")
+	}, time.Minute)
+
+	// Negative results are cached without an OnSet event
+	c.GetOrSet(ctx, "gone", func() (interface{}, error) {
+		return nil, cache.ErrNotExist
+	}, time.Minute)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expected := map[string][]string{
+		"hit":    {"users:a"},
+		"miss":   {"users:missing", "failing", "gone"},
+		"set":    {"users:a", "b"},
+		"evict":  {"users:a"},
+		"expire": {"b"},
+		"error":  {"load:failing"},
+	}
+	for event, keys := range expected {
+		got := events[event]
+		if len(got) != len(keys) {
+			t.Errorf("Expected %s events %v, got %v", event, keys, got)
+			continue
+		}
+		for i := range keys {
+			if got[i] != keys[i] {
+				t.Errorf("Expected %s events %v, got %v", event, keys, got)
+				break
+			}
+		}
+	}
+}
+
+func TestOnErrorFromStore(t *testing.T) {
+	ctx := context.Background()
+
+	c := cache.NewWithStore(failingStore{cache.NewMemoryStore(time.Minute)}, cache.DefaultConfig())
+	defer c.Close()
+
+	var failed []cache.Operation
+	c.OnError(func(ctx context.Context, op cache.Operation, key string, err error) {
+		failed = append(failed, op)
+	})
+
+	if err := c.Set(ctx, "key", "value"); err == nil {
+		t.Fatal("Expected Set to fail")
+	}
+
+	// Misses are not errors
+	c.Get(ctx, "key")
+
+	if len(failed) != 1 || failed[0] != cache.OpSet {
+		t.Errorf("Expected one set error, got %v", failed)
+	}
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// EvictionPolicy decides which entry a bounded MemoryStore removes when it is full.
+// The store serializes all calls, so implementations need not be thread-safe.
+type EvictionPolicy interface {
+	// Add records a newly inserted key
+	Add(key string)
+
+	// Access records a read or overwrite of an existing key
+	Access(key string)
+
+	// Remove forgets a key that was deleted, expired or evicted
+	Remove(key string)
+
+	// Victim returns the key that should be evicted next
+	Victim() (string, bool)
+}
+
+// listPolicy keeps keys in a list ordered from most to least recently inserted
+// (or used, when moveOnAccess is set)
+type listPolicy struct {
+	order        *list.List
+	elements     map[string]*list.Element
+	moveOnAccess bool
+}
+
+// NewLRUPolicy evicts the least recently used key
+func NewLRUPolicy() EvictionPolicy {
+	return &listPolicy{
+		order:        list.New(),
+		elements:     make(map[string]*list.Element),
+		moveOnAccess: true,
+	}
+}
+
+// NewFIFOPolicy evicts the oldest inserted key regardless of access
+func NewFIFOPolicy() EvictionPolicy {
+	return &listPolicy{
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// Add records a newly inserted key
+func (p *listPolicy) Add(key string) {
+	if elem, found := p.elements[key]; found {
+		p.order.MoveToFront(elem)
+		return
+	}
+	p.elements[key] = p.order.PushFront(key)
+}
+
+// Access marks a key as recently used
+func (p *listPolicy) Access(key string) {
+	if !p.moveOnAccess {
+		return
+	}
+	if elem, found := p.elements[key]; found {
+		p.order.MoveToFront(elem)
+	}
+}
+
+// Remove forgets a key
+func (p *listPolicy) Remove(key string) {
+	if elem, found := p.elements[key]; found {
+		p.order.Remove(elem)
+		delete(p.elements, key)
+	}
+}
+
+// Victim returns the key at the back of the list
+func (p *listPolicy) Victim() (string, bool) {
+	elem := p.order.Back()
+	if elem == nil {
+		return "", false
+	}
+	return elem.Value.(string), true
+}
+
+// lfuEntry tracks the access frequency of a key
+type lfuEntry struct {
+	key   string
+	freq  uint64
+	seq   uint64
+	index int
+}
+
+// lfuHeap orders entries by frequency, then by least recent access
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	entry := x.(*lfuEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
+
+// lfuPolicy evicts the least frequently used key
+type lfuPolicy struct {
+	entries map[string]*lfuEntry
+	heap    lfuHeap
+	seq     uint64
+}
+
+// NewLFUPolicy evicts the least frequently used key, breaking ties by recency
+func NewLFUPolicy() EvictionPolicy {
+	return &lfuPolicy{
+		entries: make(map[string]*lfuEntry),
+	}
+}
+
+// Add records a newly inserted key
+func (p *lfuPolicy) Add(key string) {
+	if _, found := p.entries[key]; found {
+		p.Access(key)
+		return
+	}
+
+	p.seq++
+	entry := &lfuEntry{key: key, freq: 1, seq: p.seq}
+	p.entries[key] = entry
+	heap.Push(&p.heap, entry)
+}
+
+// Access increments the frequency of a key
+func (p *lfuPolicy) Access(key string) {
+	entry, found := p.entries[key]
+	if !found {
+		return
+	}
+
+	p.seq++
+	entry.freq++
+	entry.seq = p.seq
+	heap.Fix(&p.heap, entry.index)
+}
+
+// Remove forgets a key
+func (p *lfuPolicy) Remove(key string) {
+	entry, found := p.entries[key]
+	if !found {
+		return
+	}
+
+	heap.Remove(&p.heap, entry.index)
+	delete(p.entries, key)
+}
+
+// Victim returns the least frequently used key
+func (p *lfuPolicy) Victim() (string, bool) {
+	if len(p.heap) == 0 {
+		return "", false
+	}
+	return p.heap[0].key, true
+}
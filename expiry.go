@@ -0,0 +1,56 @@
+package cache
+
+import "container/heap"
+
+// expiryHeap is a min-heap of items ordered by expiration time, so that
+// cleanup only visits entries that have actually expired
+type expiryHeap []*item
+
+func (h expiryHeap) Len() int { return len(h) }
+
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiration < h[j].expiration }
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	it := x.(*item)
+	it.index = len(*h)
+	*h = append(*h, it)
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*h = old[:n-1]
+	return it
+}
+
+// track adds an item with an expiration to the heap
+func (h *expiryHeap) track(it *item) {
+	it.index = -1
+	if it.expiration > 0 {
+		heap.Push(h, it)
+	}
+}
+
+// untrack removes an item from the heap if it is tracked
+func (h *expiryHeap) untrack(it *item) {
+	if it.index >= 0 {
+		heap.Remove(h, it.index)
+	}
+}
+
+// next returns the item that expires first
+func (h expiryHeap) next() (*item, bool) {
+	if len(h) == 0 {
+		return nil, false
+	}
+	return h[0], true
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// invalidationPingInterval is how often an idle subscription is health-checked
+	invalidationPingInterval = 30 * time.Second
+
+	// invalidationRetryInterval is how long to wait before resubscribing after an error
+	invalidationRetryInterval = 1 * time.Second
+)
+
+// invalidationMessage is published whenever an instance changes shared data
+type invalidationMessage struct {
+	Origin string   `json:"o"`
+	All    bool     `json:"a,omitempty"`
+	Keys   []string `json:"k,omitempty"`
+	Prefix string   `json:"p,omitempty"`
+}
+
+// InvalidationBus keeps local caches of Redis data coherent across instances.
+// Every write, delete or clear is published on a Redis channel, and keys
+// published by other instances are dropped from the local store. If the
+// subscription is lost, invalidations may have been missed, so the local
+// store is flushed entirely.
+type InvalidationBus struct {
+	client  redis.UniversalClient
+	channel string
+	local   Store
+	origin  string
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewInvalidationBus subscribes to channel and drops invalidated keys from local
+func NewInvalidationBus(redisStore *RedisStore, local Store, channel string) (*InvalidationBus, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bus := &InvalidationBus{
+		client:  redisStore.client,
+		channel: channel,
+		local:   local,
+		origin:  hex.EncodeToString(buf),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go bus.subscribe(ctx)
+
+	return bus, nil
+}
+
+// Invalidate tells other instances to drop the given keys
+func (b *InvalidationBus) Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return b.publish(ctx, invalidationMessage{Origin: b.origin, Keys: keys})
+}
+
+// InvalidateAll tells other instances to flush their local store
+func (b *InvalidationBus) InvalidateAll(ctx context.Context) error {
+	return b.publish(ctx, invalidationMessage{Origin: b.origin, All: true})
+}
+
+// InvalidatePrefix tells other instances to drop all keys starting with prefix
+func (b *InvalidationBus) InvalidatePrefix(ctx context.Context, prefix string) error {
+	return b.publish(ctx, invalidationMessage{Origin: b.origin, Prefix: prefix})
+}
+
+// Close stops the subscription
+func (b *InvalidationBus) Close() error {
+	b.cancel()
+	<-b.done
+	return nil
+}
+
+// publish sends an invalidation message
+func (b *InvalidationBus) publish(ctx context.Context, msg invalidationMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(ctx, b.channel, payload).Err()
+}
+
+// subscribe receives invalidations until the bus is closed, resubscribing on errors
+func (b *InvalidationBus) subscribe(ctx context.Context) {
+	defer close(b.done)
+
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	defer pubsub.Close()
+
+	subscribed := false
+	lost := false
+
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, invalidationPingInterval)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// An idle subscription is fine as long as the connection answers pings
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && pubsub.Ping(ctx) == nil {
+				continue
+			}
+
+			// Invalidations may be missed until we resubscribe
+			if subscribed {
+				b.local.Clear(ctx)
+				subscribed = false
+				lost = true
+			}
+
+			select {
+			case <-time.After(invalidationRetryInterval):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" {
+				if lost {
+					// Drop anything written between the flush and the resubscription
+					b.local.Clear(ctx)
+					lost = false
+				}
+				subscribed = true
+			}
+
+		case *redis.Message:
+			b.handle(ctx, m.Payload)
+		}
+	}
+}
+
+// handle applies an invalidation published by another instance
+func (b *InvalidationBus) handle(ctx context.Context, payload string) {
+	var msg invalidationMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		return
+	}
+
+	if msg.Origin == b.origin {
+		return
+	}
+
+	if msg.All {
+		b.local.Clear(ctx)
+		return
+	}
+
+	if msg.Prefix != "" {
+		if deleter, ok := b.local.(PrefixDeleter); ok {
+			deleter.DeletePrefix(ctx, msg.Prefix)
+		} else {
+			b.local.Clear(ctx)
+		}
+		return
+	}
+
+	for _, key := range msg.Keys {
+		b.local.Delete(ctx, key)
+	}
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLockHeld is returned by TryLock when another owner holds the lock
+	ErrLockHeld = errors.New("lock is held by another owner")
+
+	// ErrLockLost is returned when a lock expired or was taken over before
+	// it was extended or released
+	ErrLockLost = errors.New("lock was lost")
+
+	// ErrLocksNotSupported is returned when the store does not implement LockStore
+	ErrLocksNotSupported = errors.New("store does not support locks")
+
+	// ErrInvalidLockTTL is returned when a lock is taken or extended without a positive TTL
+	ErrInvalidLockTTL = errors.New("lock TTL must be positive")
+)
+
+// LockOptions configures a Locker
+type LockOptions struct {
+	// RetryInterval is how often Lock retries while the lock is held elsewhere
+	// Default: 50ms
+	RetryInterval time.Duration
+
+	// AutoRenew extends held locks in the background every third of their TTL
+	// until they are released or found lost
+	// Default: false
+	AutoRenew bool
+}
+
+// Locker takes leased locks on the backend of a cache: SET NX PX on Redis,
+// a lock table on the memory backend. On a namespaced cache, lock keys are
+// scoped to the namespace.
+type Locker struct {
+	store  LockStore
+	prefix string
+	opts   LockOptions
+}
+
+// Locker returns a Locker using the backend of the cache. Its methods fail with
+// ErrLocksNotSupported if the store does not implement LockStore.
+func (c *Cache) Locker(opts LockOptions) *Locker {
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = lockPollInterval
+	}
+
+	store, _ := findStore[LockStore](c.store)
+	return &Locker{
+		store:  store,
+		prefix: c.prefix,
+		opts:   opts,
+	}
+}
+
+// Lock takes the lock on key for ttl, waiting until it is free or ctx is done
+func (l *Locker) Lock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	for {
+		lock, err := l.TryLock(ctx, key, ttl)
+		if !errors.Is(err, ErrLockHeld) {
+			return lock, err
+		}
+
+		select {
+		case <-time.After(l.opts.RetryInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// TryLock takes the lock on key for ttl, failing with ErrLockHeld if another owner holds it
+func (l *Locker) TryLock(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	if l.store == nil {
+		return nil, ErrLocksNotSupported
+	}
+	if ttl <= 0 {
+		return nil, ErrInvalidLockTTL
+	}
+
+	owner, err := newLockOwner()
+	if err != nil {
+		return nil, err
+	}
+
+	token, ok, err := l.store.AcquireLock(ctx, l.prefix+key, owner, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLockHeld
+	}
+
+	lock := &Lock{
+		store: l.store,
+		key:   l.prefix + key,
+		owner: owner,
+		token: token,
+		ttl:   ttl,
+		lost:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if l.opts.AutoRenew {
+		lock.renewing.Add(1)
+		go lock.renew(time.Now().Add(ttl))
+	}
+	return lock, nil
+}
+
+// newLockOwner returns a random owner identifier
+func newLockOwner() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Lock is a held lock
+type Lock struct {
+	store LockStore
+	key   string
+	owner string
+	token int64
+
+	mu       sync.Mutex
+	ttl      time.Duration
+	lost     chan struct{}
+	lostOnce sync.Once
+	done     chan struct{}
+	doneOnce sync.Once
+	renewing sync.WaitGroup
+}
+
+// Key returns the locked key, including any namespace prefix
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Token returns the fencing token of this acquisition. Tokens increase with
+// every acquisition of the key, so a resource that remembers the highest token
+// it has seen can reject writes from holders whose lease already ran out.
+// On Redis the counter behind them is kept per lock key without expiry.
+func (l *Lock) Token() int64 {
+	return l.token
+}
+
+// Lost is closed once the lock is known to be lost, either because Extend or
+// Unlock found it expired or because auto-renewal failed. Work guarded by the
+// lock should stop when it is closed.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Extend resets the lease to ttl, failing with ErrLockLost if the lock was lost
+func (l *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return ErrInvalidLockTTL
+	}
+
+	held, err := l.store.ExtendLock(ctx, l.key, l.owner, ttl)
+	if err != nil {
+		return err
+	}
+	if !held {
+		l.markLost()
+		return ErrLockLost
+	}
+
+	l.mu.Lock()
+	l.ttl = ttl
+	l.mu.Unlock()
+	return nil
+}
+
+// Unlock stops auto-renewal and releases the lock, failing with ErrLockLost
+// if it had already expired or been taken over
+func (l *Lock) Unlock(ctx context.Context) error {
+	l.doneOnce.Do(func() { close(l.done) })
+	l.renewing.Wait()
+
+	held, err := l.store.ReleaseLock(ctx, l.key, l.owner)
+	if err != nil {
+		return err
+	}
+	if !held {
+		l.markLost()
+		return ErrLockLost
+	}
+	return nil
+}
+
+// markLost closes the Lost channel
+func (l *Lock) markLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+// renew extends the lock every third of its TTL until it is released.
+// Failed extensions are retried until the lease would have run out.
+func (l *Lock) renew(expires time.Time) {
+	defer l.renewing.Done()
+
+	for {
+		l.mu.Lock()
+		ttl := l.ttl
+		l.mu.Unlock()
+
+		select {
+		case <-time.After(ttl / 3):
+		case <-l.done:
+			return
+		case <-l.lost:
+			return
+		}
+
+		start := time.Now()
+		ctx, cancel := context.WithDeadline(context.Background(), expires)
+		err := l.Extend(ctx, ttl)
+		cancel()
+
+		switch {
+		case err == nil:
+			expires = start.Add(ttl)
+		case errors.Is(err, ErrLockLost):
+			return
+		case !time.Now().Before(expires):
+			l.markLost()
+			return
+		}
+	}
+}
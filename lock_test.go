@@ -0,0 +1,197 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+// testLocker checks lock semantics shared by every backend
+func testLocker(t *testing.T, c *cache.Cache) {
+	ctx := context.Background()
+	locker := c.Locker(cache.LockOptions{RetryInterval: 5 * time.Millisecond})
+
+	if _, err := locker.TryLock(ctx, "job", 0); !errors.Is(err, cache.ErrInvalidLockTTL) {
+		t.Errorf("Expected ErrInvalidLockTTL, got %v", err)
+	}
+
+	first, err := locker.TryLock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+	if _, err := locker.TryLock(ctx, "job", time.Minute); !errors.Is(err, cache.ErrLockHeld) {
+		t.Errorf("Expected ErrLockHeld, got %v", err)
+	}
+
+	// Lock waits for the holder to release
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		first.Unlock(ctx)
+	}()
+	second, err := locker.Lock(ctx, "job", 30*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	if second.Token() <= first.Token() {
+		t.Errorf("Expected increasing fencing tokens, got %d then %d", first.Token(), second.Token())
+	}
+
+	// Once the lease runs out another owner can take over
+	time.Sleep(50 * time.Millisecond)
+	third, err := locker.TryLock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock after expiry failed: %v", err)
+	}
+	if err := second.Extend(ctx, time.Minute); !errors.Is(err, cache.ErrLockLost) {
+		t.Errorf("Expected ErrLockLost from Extend, got %v", err)
+	}
+	if err := second.Unlock(ctx); !errors.Is(err, cache.ErrLockLost) {
+		t.Errorf("Expected ErrLockLost from Unlock, got %v", err)
+	}
+	select {
+	case <-second.Lost():
+	default:
+		t.Error("Expected Lost to be closed")
+	}
+
+	if err := third.Unlock(ctx); err != nil {
+		t.Errorf("Unlock failed: %v", err)
+	}
+
+	// Auto-renewal keeps the lock past its TTL
+	renewing := c.Locker(cache.LockOptions{AutoRenew: true})
+	renewed, err := renewing.TryLock(ctx, "renewed", 30*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := locker.TryLock(ctx, "renewed", time.Minute); !errors.Is(err, cache.ErrLockHeld) {
+		t.Errorf("Expected renewed lock to be held, got %v", err)
+	}
+	if err := renewed.Unlock(ctx); err != nil {
+		t.Errorf("Unlock of renewed lock failed: %v", err)
+	}
+}
+
+func TestLocker(t *testing.T) {
+	c, err := cache.New(cache.DefaultConfig().WithShards(4))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	testLocker(t, c)
+}
+
+func TestLockerNamespace(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	lock, err := c.Namespace("a").Locker(cache.LockOptions{}).TryLock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+	if lock.Key() != "a:job" {
+		t.Errorf("Expected namespaced lock key, got %s", lock.Key())
+	}
+
+	if _, err := c.Namespace("b").Locker(cache.LockOptions{}).TryLock(ctx, "job", time.Minute); err != nil {
+		t.Errorf("Expected locks in other namespaces to be independent, got %v", err)
+	}
+}
+
+func TestLockerReclaim(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(&cache.Config{
+		Backend:         cache.BackendMemory,
+		DefaultTTL:      time.Minute,
+		CleanupInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	locker := c.Locker(cache.LockOptions{})
+	first, err := locker.TryLock(ctx, "job", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryLock failed: %v", err)
+	}
+
+	// Fencing tokens keep increasing once expired locks are reclaimed
+	time.Sleep(50 * time.Millisecond)
+	second, err := locker.TryLock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("TryLock after reclaim failed: %v", err)
+	}
+	if second.Token() <= first.Token() {
+		t.Errorf("Expected increasing fencing tokens, got %d then %d", first.Token(), second.Token())
+	}
+}
+
+func TestLockerRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL not set")
+	}
+
+	c, err := cache.New(&cache.Config{
+		Backend:    cache.BackendRedis,
+		RedisURL:   redisURL,
+		DefaultTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	testLocker(t, c.Namespace("locktest"))
+}
+
+func TestStampedeLockRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL not set")
+	}
+
+	c, err := cache.New(cache.DefaultConfig().
+		WithBackend(cache.BackendRedis).
+		WithRedisURL(redisURL).
+		WithStampedeLock(time.Second))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	ns := c.Namespace("stampedetest")
+	ns.Delete(ctx, "user:42")
+
+	// A user lock on the key must not hold up loading it
+	lock, err := ns.Locker(cache.LockOptions{}).TryLock(ctx, "user:42", time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+	defer lock.Unlock(ctx)
+
+	start := time.Now()
+	value, err := ns.GetOrSet(ctx, "user:42", func() (interface{}, error) {
+		return "alice", nil
+	}, time.Minute)
+	if err != nil || value != "alice" {
+		t.Fatalf("Expected alice, got %v (%v)", value, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("GetOrSet waited %v for a user lock", elapsed)
+	}
+}
@@ -4,19 +4,60 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
 	// ErrNotFound is returned when a key is not found
 	ErrNotFound = errors.New("key not found")
+
+	// ErrItemTooLarge is returned when a single value exceeds MaxItemBytes or MaxBytes
+	ErrItemTooLarge = errors.New("item exceeds maximum size")
 )
 
+// Sizer returns the approximate size of a value in bytes
+type Sizer func(value interface{}) int64
+
+// DefaultSizer is exact for strings and byte slices, uses fixed sizes for
+// numbers and falls back to the JSON encoded length for everything else
+func DefaultSizer(value interface{}) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	case bool, int8, uint8:
+		return 1
+	case int16, uint16:
+		return 2
+	case int32, uint32, float32:
+		return 4
+	case int, int64, uint, uint64, uintptr, float64, complex64:
+		return 8
+	case complex128:
+		return 16
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return 0
+		}
+		return int64(len(data))
+	}
+}
+
 // item represents a cached item
 type item struct {
+	key        string
 	value      interface{}
 	expiration int64
+	size       int64
+	tags       []string
+	index      int // position in the expiry heap, -1 if untracked
 }
 
 // isExpired checks if the item has expired
@@ -27,20 +68,122 @@ func (i *item) isExpired() bool {
 	return time.Now().UnixNano() > i.expiration
 }
 
+// MemoryOptions configures a MemoryStore
+type MemoryOptions struct {
+	// CleanupInterval is how often expired entries are removed
+	// Default: 10 minutes
+	CleanupInterval time.Duration
+
+	// CleanupBudget caps how many expired entries are reclaimed per cleanup tick;
+	// the rest are reclaimed on later ticks. 0 means no cap
+	CleanupBudget int
+
+	// MaxEntries bounds the number of entries; 0 means unbounded
+	MaxEntries int
+
+	// MaxBytes bounds the approximate total size of keys and values; 0 means unbounded.
+	// Single entries larger than this are rejected with ErrItemTooLarge.
+	MaxBytes int64
+
+	// MaxItemBytes rejects single entries larger than this with ErrItemTooLarge; 0 means no limit
+	MaxItemBytes int64
+
+	// Sizer estimates value sizes for MaxBytes and MaxItemBytes
+	// Default: DefaultSizer
+	Sizer Sizer
+
+	// EvictionPolicy creates the policy used once MaxEntries or MaxBytes is reached
+	// Default: NewLRUPolicy
+	EvictionPolicy func() EvictionPolicy
+
+	// OnEvict is called with each entry evicted to respect MaxEntries or MaxBytes,
+	// after the store lock is released
+	OnEvict func(key string, value interface{})
+
+	// OnExpire is called with each expired entry reclaimed by the cleanup goroutine,
+	// after the store lock is released
+	OnExpire func(key string, value interface{})
+}
+
 // MemoryStore implements an in-memory cache
 type MemoryStore struct {
 	items   map[string]*item
+	tags    map[string]map[string]struct{}
 	mu      sync.RWMutex
 	cleanup time.Duration
 	stop    chan bool
+
+	expiries      expiryHeap
+	cleanupBudget int
+
+	maxEntries   int
+	maxBytes     int64
+	maxItemBytes int64
+	bytes        int64
+	sizer        Sizer
+	newPolicy    func() EvictionPolicy
+	policy       EvictionPolicy
+	policyMu     sync.Mutex
+	evictions    uint64
+	expirations  uint64
+
+	onEvict  func(key string, value interface{})
+	onExpire func(key string, value interface{})
+	evicted  []*item // evicted entries awaiting onEvict, guarded by mu
+
+	locks  map[string]memoryLock
+	fence  int64 // last fencing token, shared by all keys
+	lockMu sync.Mutex
+
+	limits  map[string]*rateLimitState
+	limitMu sync.Mutex
+}
+
+// memoryLock is a lease held in the lock table of a MemoryStore
+type memoryLock struct {
+	owner   string
+	expires time.Time
 }
 
 // NewMemoryStore creates a new in-memory cache
 func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
+	return NewMemoryStoreWithOptions(MemoryOptions{
+		CleanupInterval: cleanupInterval,
+	})
+}
+
+// NewMemoryStoreWithOptions creates a new in-memory cache, optionally bounded
+func NewMemoryStoreWithOptions(opts MemoryOptions) *MemoryStore {
+	if opts.CleanupInterval <= 0 {
+		opts.CleanupInterval = DefaultConfig().CleanupInterval
+	}
+
+	if opts.Sizer == nil {
+		opts.Sizer = DefaultSizer
+	}
+
 	store := &MemoryStore{
-		items:   make(map[string]*item),
-		cleanup: cleanupInterval,
-		stop:    make(chan bool),
+		items:         make(map[string]*item),
+		tags:          make(map[string]map[string]struct{}),
+		locks:         make(map[string]memoryLock),
+		limits:        make(map[string]*rateLimitState),
+		cleanup:       opts.CleanupInterval,
+		cleanupBudget: opts.CleanupBudget,
+		stop:          make(chan bool),
+		maxEntries:    opts.MaxEntries,
+		maxBytes:      opts.MaxBytes,
+		maxItemBytes:  opts.MaxItemBytes,
+		sizer:         opts.Sizer,
+		onEvict:       opts.OnEvict,
+		onExpire:      opts.OnExpire,
+	}
+
+	if opts.MaxEntries > 0 || opts.MaxBytes > 0 {
+		store.newPolicy = opts.EvictionPolicy
+		if store.newPolicy == nil {
+			store.newPolicy = NewLRUPolicy
+		}
+		store.policy = store.newPolicy()
 	}
 
 	// Start cleanup goroutine
@@ -63,25 +206,76 @@ func (m *MemoryStore) Get(ctx context.Context, key string) (interface{}, error)
 		return nil, ErrNotFound
 	}
 
+	m.touch(key)
 	return item.value, nil
 }
 
+// GetWithTTL retrieves a value and its remaining TTL, 0 if it does not expire
+func (m *MemoryStore) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	item, found := m.items[key]
+	if !found || item.isExpired() {
+		return nil, 0, ErrNotFound
+	}
+
+	var ttl time.Duration
+	if item.expiration > 0 {
+		ttl = time.Until(time.Unix(0, item.expiration))
+	}
+
+	m.touch(key)
+	return item.value, ttl, nil
+}
+
 // Set stores a value in the cache
 func (m *MemoryStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	defer m.unlock()
 
 	var expiration int64
 	if ttl > 0 {
 		expiration = time.Now().Add(ttl).UnixNano()
 	}
 
-	m.items[key] = &item{
+	return m.insert(key, &item{
 		value:      value,
 		expiration: expiration,
+	})
+}
+
+// SetWithTags stores a value and associates the key with the given tags.
+// Overwriting the key replaces its tags.
+func (m *MemoryStore) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	m.mu.Lock()
+	defer m.unlock()
+
+	var expiration int64
+	if ttl > 0 {
+		expiration = time.Now().Add(ttl).UnixNano()
 	}
 
-	return nil
+	return m.insert(key, &item{
+		value:      value,
+		expiration: expiration,
+		tags:       tags,
+	})
+}
+
+// InvalidateTags removes all keys associated with any of the tags
+func (m *MemoryStore) InvalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var removed []string
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			m.remove(key)
+			removed = append(removed, key)
+		}
+	}
+	return removed, nil
 }
 
 // Delete removes a value from the cache
@@ -89,7 +283,20 @@ func (m *MemoryStore) Delete(ctx context.Context, key string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	delete(m.items, key)
+	m.remove(key)
+	return nil
+}
+
+// DeletePrefix removes all keys starting with prefix
+func (m *MemoryStore) DeletePrefix(ctx context.Context, prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.items {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(key)
+		}
+	}
 	return nil
 }
 
@@ -109,7 +316,7 @@ func (m *MemoryStore) Has(ctx context.Context, key string) bool {
 // Increment increments a numeric value
 func (m *MemoryStore) Increment(ctx context.Context, key string, delta int64) (int64, error) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	defer m.unlock()
 
 	existingItem, found := m.items[key]
 	var current int64
@@ -121,9 +328,11 @@ func (m *MemoryStore) Increment(ctx context.Context, key string, delta int64) (i
 	}
 
 	newValue := current + delta
-	m.items[key] = &item{
+	if err := m.insert(key, &item{
 		value:      newValue,
 		expiration: 0,
+	}); err != nil {
+		return 0, err
 	}
 
 	return newValue, nil
@@ -140,9 +349,109 @@ func (m *MemoryStore) Clear(ctx context.Context) error {
 	defer m.mu.Unlock()
 
 	m.items = make(map[string]*item)
+	m.tags = make(map[string]map[string]struct{})
+	m.expiries = nil
+	m.bytes = 0
+	if m.policy != nil {
+		m.policyMu.Lock()
+		m.policy = m.newPolicy()
+		m.policyMu.Unlock()
+	}
 	return nil
 }
 
+// AcquireLock takes the lock on key if it is free or its lease ran out.
+// Locks are kept apart from cached entries and survive Clear. Fencing tokens
+// come from one counter for the whole store, so they increase per key as
+// well, but only within this process.
+func (m *MemoryStore) AcquireLock(ctx context.Context, key, owner string, ttl time.Duration) (int64, bool, error) {
+	m.lockMu.Lock()
+	defer m.lockMu.Unlock()
+
+	now := time.Now()
+	if held, found := m.locks[key]; found && now.Before(held.expires) {
+		return 0, false, nil
+	}
+
+	m.locks[key] = memoryLock{owner: owner, expires: now.Add(ttl)}
+	m.fence++
+	return m.fence, true, nil
+}
+
+// ExtendLock resets the lease of a lock still held by owner
+func (m *MemoryStore) ExtendLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	m.lockMu.Lock()
+	defer m.lockMu.Unlock()
+
+	now := time.Now()
+	held, found := m.locks[key]
+	if !found || held.owner != owner || !now.Before(held.expires) {
+		return false, nil
+	}
+
+	m.locks[key] = memoryLock{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// ReleaseLock releases a lock still held by owner
+func (m *MemoryStore) ReleaseLock(ctx context.Context, key, owner string) (bool, error) {
+	m.lockMu.Lock()
+	defer m.lockMu.Unlock()
+
+	held, found := m.locks[key]
+	if !found || held.owner != owner {
+		return false, nil
+	}
+
+	delete(m.locks, key)
+	return time.Now().Before(held.expires), nil
+}
+
+// reclaimLocks drops locks whose lease ran out
+func (m *MemoryStore) reclaimLocks() {
+	m.lockMu.Lock()
+	defer m.lockMu.Unlock()
+
+	now := time.Now()
+	for key, held := range m.locks {
+		if !now.Before(held.expires) {
+			delete(m.locks, key)
+		}
+	}
+}
+
+// AllowN records n requests against key if limit allows all of them.
+// Rate limit state is kept apart from cached entries and survives Clear.
+func (m *MemoryStore) AllowN(ctx context.Context, key string, limit RateLimit, n int64) (RateLimitResult, error) {
+	m.limitMu.Lock()
+	defer m.limitMu.Unlock()
+
+	key = rateLimitKey(key, limit)
+	state, found := m.limits[key]
+	if !found {
+		state = &rateLimitState{}
+	}
+
+	result := limit.apply(state, time.Now().UnixNano(), n)
+	if result.Allowed && !found {
+		m.limits[key] = state
+	}
+	return result, nil
+}
+
+// reclaimRateLimits drops rate limit state that no longer affects any request
+func (m *MemoryStore) reclaimRateLimits() {
+	m.limitMu.Lock()
+	defer m.limitMu.Unlock()
+
+	now := time.Now().UnixNano()
+	for key, state := range m.limits {
+		if state.expires <= now {
+			delete(m.limits, key)
+		}
+	}
+}
+
 // Close stops the cleanup goroutine
 func (m *MemoryStore) Close() error {
 	m.stop <- true
@@ -157,13 +466,9 @@ func (m *MemoryStore) cleanupExpired() {
 	for {
 		select {
 		case <-ticker.C:
-			m.mu.Lock()
-			for key, item := range m.items {
-				if item.isExpired() {
-					delete(m.items, key)
-				}
-			}
-			m.mu.Unlock()
+			m.reclaimExpired()
+			m.reclaimLocks()
+			m.reclaimRateLimits()
 
 		case <-m.stop:
 			return
@@ -171,6 +476,220 @@ func (m *MemoryStore) cleanupExpired() {
 	}
 }
 
+// cleanupBatch is how many expired entries are reclaimed per write lock,
+// so readers are never blocked for long even when many keys expire at once
+const cleanupBatch = 256
+
+// reclaimExpired removes expired entries in order of expiration, doing work
+// proportional to the number of expired entries rather than the store size
+func (m *MemoryStore) reclaimExpired() int {
+	reclaimed := 0
+
+	for m.cleanupBudget <= 0 || reclaimed < m.cleanupBudget {
+		batch := cleanupBatch
+		if m.cleanupBudget > 0 && m.cleanupBudget-reclaimed < batch {
+			batch = m.cleanupBudget - reclaimed
+		}
+
+		var expired []*item
+		m.mu.Lock()
+		n := 0
+		for n < batch {
+			next, ok := m.expiries.next()
+			if !ok || !next.isExpired() {
+				break
+			}
+			m.remove(next.key)
+			if m.onExpire != nil {
+				expired = append(expired, next)
+			}
+			n++
+		}
+		m.mu.Unlock()
+
+		for _, it := range expired {
+			m.onExpire(it.key, it.value)
+		}
+
+		atomic.AddUint64(&m.expirations, uint64(n))
+		reclaimed += n
+		if n < batch {
+			break
+		}
+	}
+
+	return reclaimed
+}
+
+// Len returns the number of entries, including expired ones not yet cleaned up
+func (m *MemoryStore) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.items)
+}
+
+// Bytes returns the approximate total size of all entries
+func (m *MemoryStore) Bytes() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.bytes
+}
+
+// Evictions returns how many entries were evicted to respect MaxEntries or MaxBytes
+func (m *MemoryStore) Evictions() uint64 {
+	return atomic.LoadUint64(&m.evictions)
+}
+
+// Expirations returns how many expired entries were reclaimed by the cleanup goroutine
+func (m *MemoryStore) Expirations() uint64 {
+	return atomic.LoadUint64(&m.expirations)
+}
+
+// overBudget reports whether the given totals exceed MaxEntries or MaxBytes
+func (m *MemoryStore) overBudget(entries int, bytes int64) bool {
+	return (m.maxEntries > 0 && entries > m.maxEntries) ||
+		(m.maxBytes > 0 && bytes > m.maxBytes)
+}
+
+// insert stores an item, evicting entries first if the store is full.
+// The caller must hold the write lock.
+func (m *MemoryStore) insert(key string, it *item) error {
+	it.key = key
+	it.size = int64(len(key)) + m.sizer(it.value)
+	if (m.maxItemBytes > 0 && it.size > m.maxItemBytes) || (m.maxBytes > 0 && it.size > m.maxBytes) {
+		return ErrItemTooLarge
+	}
+
+	old, found := m.items[key]
+	if found {
+		if !m.overBudget(len(m.items), m.bytes-old.size+it.size) {
+			m.expiries.untrack(old)
+			m.untag(old)
+			m.expiries.track(it)
+			m.tag(it)
+			m.items[key] = it
+			m.bytes += it.size - old.size
+			m.touch(key)
+			return nil
+		}
+
+		// Detach the old entry so it can't be chosen as its own victim
+		m.remove(key)
+	}
+
+	for m.overBudget(len(m.items)+1, m.bytes+it.size) {
+		if !m.evict() {
+			break
+		}
+	}
+
+	m.expiries.track(it)
+	m.tag(it)
+	m.items[key] = it
+	m.bytes += it.size
+	if m.policy != nil {
+		m.policyMu.Lock()
+		m.policy.Add(key)
+		m.policyMu.Unlock()
+	}
+	return nil
+}
+
+// evict removes the entry chosen by the eviction policy.
+// The caller must hold the write lock.
+func (m *MemoryStore) evict() bool {
+	if m.policy == nil {
+		return false
+	}
+
+	m.policyMu.Lock()
+	victim, ok := m.policy.Victim()
+	if ok {
+		m.policy.Remove(victim)
+	}
+	m.policyMu.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	if it, found := m.items[victim]; found {
+		m.expiries.untrack(it)
+		m.untag(it)
+		delete(m.items, victim)
+		m.bytes -= it.size
+		atomic.AddUint64(&m.evictions, 1)
+		if m.onEvict != nil {
+			m.evicted = append(m.evicted, it)
+		}
+	}
+	return true
+}
+
+// unlock releases the write lock, then reports entries evicted while it was held
+func (m *MemoryStore) unlock() {
+	evicted := m.evicted
+	m.evicted = nil
+	m.mu.Unlock()
+
+	for _, it := range evicted {
+		m.onEvict(it.key, it.value)
+	}
+}
+
+// remove deletes an entry and forgets it in the eviction policy.
+// The caller must hold the write lock.
+func (m *MemoryStore) remove(key string) {
+	if it, found := m.items[key]; found {
+		m.expiries.untrack(it)
+		m.untag(it)
+		m.bytes -= it.size
+		delete(m.items, key)
+	}
+	if m.policy != nil {
+		m.policyMu.Lock()
+		m.policy.Remove(key)
+		m.policyMu.Unlock()
+	}
+}
+
+// tag adds an item to the index of each of its tags.
+// The caller must hold the write lock.
+func (m *MemoryStore) tag(it *item) {
+	for _, tag := range it.tags {
+		keys, found := m.tags[tag]
+		if !found {
+			keys = make(map[string]struct{})
+			m.tags[tag] = keys
+		}
+		keys[it.key] = struct{}{}
+	}
+}
+
+// untag removes an item from the index of each of its tags, dropping
+// tags that no longer have any keys.
+// The caller must hold the write lock.
+func (m *MemoryStore) untag(it *item) {
+	for _, tag := range it.tags {
+		keys := m.tags[tag]
+		delete(keys, it.key)
+		if len(keys) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+}
+
+// touch records an access in the eviction policy
+func (m *MemoryStore) touch(key string) {
+	if m.policy != nil {
+		m.policyMu.Lock()
+		m.policy.Access(key)
+		m.policyMu.Unlock()
+	}
+}
+
 // MarshalJSON for JSON encoding support
 func (m *MemoryStore) marshalValue(value interface{}) ([]byte, error) {
 	return json.Marshal(value)
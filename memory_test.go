@@ -0,0 +1,261 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+func TestEvictionPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		policy  func() cache.EvictionPolicy
+		evicted string
+	}{
+		// key1 is read, key2 is read twice, key3 is never read
+		{name: "LRU", policy: cache.NewLRUPolicy, evicted: "key3"},
+		{name: "LFU", policy: cache.NewLFUPolicy, evicted: "key3"},
+		{name: "FIFO", policy: cache.NewFIFOPolicy, evicted: "key1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := cache.NewMemoryStoreWithOptions(cache.MemoryOptions{
+				MaxEntries:     3,
+				EvictionPolicy: tt.policy,
+			})
+			defer store.Close()
+
+			store.Set(ctx, "key1", "value1", 0)
+			store.Set(ctx, "key2", "value2", 0)
+			store.Set(ctx, "key3", "value3", 0)
+
+			store.Get(ctx, "key2")
+			store.Get(ctx, "key1")
+			store.Get(ctx, "key2")
+
+			// Inserting a fourth key should evict exactly one entry
+			store.Set(ctx, "key4", "value4", 0)
+
+			if store.Len() != 3 {
+				t.Errorf("Expected 3 entries, got %d", store.Len())
+			}
+			if store.Has(ctx, tt.evicted) {
+				t.Errorf("Expected %s to be evicted", tt.evicted)
+			}
+			if !store.Has(ctx, "key4") {
+				t.Error("key4 should exist")
+			}
+			if store.Evictions() != 1 {
+				t.Errorf("Expected 1 eviction, got %d", store.Evictions())
+			}
+		})
+	}
+}
+
+func TestBoundedOverwrite(t *testing.T) {
+	ctx := context.Background()
+
+	store := cache.NewMemoryStoreWithOptions(cache.MemoryOptions{
+		MaxEntries: 2,
+	})
+	defer store.Close()
+
+	// Overwriting existing keys should never evict
+	for i := 0; i < 10; i++ {
+		store.Set(ctx, "key1", i, 0)
+		store.Set(ctx, "key2", i, 0)
+	}
+
+	if store.Evictions() != 0 {
+		t.Errorf("Expected 0 evictions, got %d", store.Evictions())
+	}
+
+	// Deleted keys should free their slot
+	store.Delete(ctx, "key1")
+	store.Set(ctx, "key3", "value3", 0)
+
+	if store.Evictions() != 0 {
+		t.Errorf("Expected 0 evictions, got %d", store.Evictions())
+	}
+	if !store.Has(ctx, "key2") || !store.Has(ctx, "key3") {
+		t.Error("key2 and key3 should exist")
+	}
+}
+
+func TestByteBudgetOversized(t *testing.T) {
+	ctx := context.Background()
+
+	store := cache.NewMemoryStoreWithOptions(cache.MemoryOptions{MaxBytes: 100})
+	defer store.Close()
+
+	for i := 0; i < 5; i++ {
+		store.Set(ctx, fmt.Sprintf("key%d", i), "value", 0)
+	}
+
+	// Without MaxItemBytes, MaxBytes still bounds single entries
+	err := store.Set(ctx, "huge", make([]byte, 500), 0)
+	if !errors.Is(err, cache.ErrItemTooLarge) {
+		t.Errorf("Expected ErrItemTooLarge, got %v", err)
+	}
+	if store.Len() != 5 || store.Evictions() != 0 {
+		t.Errorf("Expected nothing evicted, got %d entries and %d evictions", store.Len(), store.Evictions())
+	}
+}
+
+func TestByteBudget(t *testing.T) {
+	ctx := context.Background()
+
+	store := cache.NewMemoryStoreWithOptions(cache.MemoryOptions{
+		MaxBytes:     100,
+		MaxItemBytes: 60,
+	})
+	defer store.Close()
+
+	// Each entry is 4 bytes of key plus 40 bytes of value
+	value := make([]byte, 40)
+	store.Set(ctx, "key1", value, 0)
+	store.Set(ctx, "key2", value, 0)
+
+	if store.Bytes() != 88 {
+		t.Errorf("Expected 88 bytes, got %d", store.Bytes())
+	}
+
+	// A third entry should evict the least recently used one
+	store.Set(ctx, "key3", value, 0)
+
+	if store.Bytes() > 100 {
+		t.Errorf("Expected at most 100 bytes, got %d", store.Bytes())
+	}
+	if store.Has(ctx, "key1") {
+		t.Error("key1 should be evicted")
+	}
+	if store.Evictions() != 1 {
+		t.Errorf("Expected 1 eviction, got %d", store.Evictions())
+	}
+
+	// Oversized entries should be rejected
+	err := store.Set(ctx, "huge", make([]byte, 100), 0)
+	if !errors.Is(err, cache.ErrItemTooLarge) {
+		t.Errorf("Expected ErrItemTooLarge, got %v", err)
+	}
+
+	// Deleting should release the bytes
+	store.Delete(ctx, "key2")
+	store.Delete(ctx, "key3")
+
+	if store.Bytes() != 0 {
+		t.Errorf("Expected 0 bytes, got %d", store.Bytes())
+	}
+}
+
+func TestCustomSizer(t *testing.T) {
+	ctx := context.Background()
+
+	store := cache.NewMemoryStoreWithOptions(cache.MemoryOptions{
+		MaxBytes: 1000,
+		Sizer: func(value interface{}) int64 {
+			return 500
+		},
+	})
+	defer store.Close()
+
+	store.Set(ctx, "a", struct{}{}, 0)
+	store.Set(ctx, "b", struct{}{}, 0)
+
+	if store.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", store.Len())
+	}
+}
+
+func TestShardedMemoryStore(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(&cache.Config{
+		Backend:    cache.BackendMemory,
+		Shards:     8,
+		MaxEntries: 800,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	store, ok := c.GetStore().(*cache.ShardedMemoryStore)
+	if !ok {
+		t.Fatalf("Expected ShardedMemoryStore, got %T", c.GetStore())
+	}
+
+	// Concurrent counters on many keys
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Increment(ctx, fmt.Sprintf("counter:%d", j), 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for j := 0; j < 100; j++ {
+		value, err := c.Get(ctx, fmt.Sprintf("counter:%d", j))
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if value != int64(16) {
+			t.Errorf("Expected 16, got %v", value)
+		}
+	}
+
+	if store.Len() != 100 {
+		t.Errorf("Expected 100 entries, got %d", store.Len())
+	}
+
+	// Clear should empty every shard
+	c.Clear(ctx)
+	if store.Len() != 0 {
+		t.Errorf("Expected 0 entries, got %d", store.Len())
+	}
+}
+
+func TestExpiryReclaim(t *testing.T) {
+	ctx := context.Background()
+
+	store := cache.NewMemoryStoreWithOptions(cache.MemoryOptions{
+		CleanupInterval: 10 * time.Millisecond,
+		CleanupBudget:   25,
+	})
+	defer store.Close()
+
+	for i := 0; i < 100; i++ {
+		store.Set(ctx, fmt.Sprintf("short:%d", i), i, time.Millisecond)
+	}
+	for i := 0; i < 5; i++ {
+		store.Set(ctx, fmt.Sprintf("long:%d", i), i, time.Hour)
+		store.Set(ctx, fmt.Sprintf("forever:%d", i), i, 0)
+	}
+
+	// Expired entries are reclaimed over several ticks
+	deadline := time.Now().Add(2 * time.Second)
+	for store.Len() > 10 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if store.Len() != 10 {
+		t.Errorf("Expected 10 entries, got %d", store.Len())
+	}
+	for i := 0; i < 5; i++ {
+		if !store.Has(ctx, fmt.Sprintf("long:%d", i)) || !store.Has(ctx, fmt.Sprintf("forever:%d", i)) {
+			t.Errorf("Unexpired entry %d should still exist", i)
+		}
+	}
+}
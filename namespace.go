@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrPrefixNotSupported is returned when clearing a namespace whose store
+// does not implement PrefixDeleter
+var ErrPrefixNotSupported = errors.New("store does not support prefix deletion")
+
+// Namespace returns a view of the cache that prefixes every key with name
+// followed by a colon. Namespaces can be nested. Clear on the view only
+// removes keys in the namespace, and Close is a no-op; close the parent instead.
+func (c *Cache) Namespace(name string) *Cache {
+	prefix := name + ":"
+
+	derived := *c
+	derived.store = &prefixStore{next: c.store, prefix: prefix}
+	derived.flight = &flightGroup{}
+	derived.prefix = c.prefix + prefix
+	return &derived
+}
+
+// Prefix returns the key prefix of a namespaced cache, or "" for the root cache
+func (c *Cache) Prefix() string {
+	return c.prefix
+}
+
+// prefixStore prefixes keys and tags before passing them to another store
+type prefixStore struct {
+	next   Store
+	prefix string
+}
+
+// Get retrieves a value
+func (p *prefixStore) Get(ctx context.Context, key string) (interface{}, error) {
+	return p.next.Get(ctx, p.prefix+key)
+}
+
+// Set stores a value
+func (p *prefixStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return p.next.Set(ctx, p.prefix+key, value, ttl)
+}
+
+// SetWithTags stores a value with tags scoped to the namespace
+func (p *prefixStore) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	tagged, ok := p.next.(TagStore)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+	return tagged.SetWithTags(ctx, p.prefix+key, value, ttl, p.prefixAll(tags))
+}
+
+// InvalidateTags removes all keys in the namespace associated with any of the tags
+func (p *prefixStore) InvalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	tagged, ok := p.next.(TagStore)
+	if !ok {
+		return nil, ErrTagsNotSupported
+	}
+
+	removed, err := tagged.InvalidateTags(ctx, p.prefixAll(tags)...)
+	for i, key := range removed {
+		removed[i] = strings.TrimPrefix(key, p.prefix)
+	}
+	return removed, err
+}
+
+// prefixAll returns the values with the namespace prefix added
+func (p *prefixStore) prefixAll(values []string) []string {
+	prefixed := make([]string, len(values))
+	for i, value := range values {
+		prefixed[i] = p.prefix + value
+	}
+	return prefixed
+}
+
+// Delete removes a value
+func (p *prefixStore) Delete(ctx context.Context, key string) error {
+	return p.next.Delete(ctx, p.prefix+key)
+}
+
+// Has checks if a key exists
+func (p *prefixStore) Has(ctx context.Context, key string) bool {
+	return p.next.Has(ctx, p.prefix+key)
+}
+
+// Increment increments a numeric value
+func (p *prefixStore) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	return p.next.Increment(ctx, p.prefix+key, delta)
+}
+
+// Decrement decrements a numeric value
+func (p *prefixStore) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	return p.next.Decrement(ctx, p.prefix+key, delta)
+}
+
+// Clear removes all keys in the namespace
+func (p *prefixStore) Clear(ctx context.Context) error {
+	return p.DeletePrefix(ctx, "")
+}
+
+// DeletePrefix removes all keys in the namespace starting with prefix
+func (p *prefixStore) DeletePrefix(ctx context.Context, prefix string) error {
+	deleter, ok := p.next.(PrefixDeleter)
+	if !ok {
+		return ErrPrefixNotSupported
+	}
+	return deleter.DeletePrefix(ctx, p.prefix+prefix)
+}
+
+// Close does nothing; the parent cache owns the store
+func (p *prefixStore) Close() error {
+	return nil
+}
+
+// Unwrap returns the wrapped store
+func (p *prefixStore) Unwrap() Store {
+	return p.next
+}
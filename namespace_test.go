@@ -0,0 +1,123 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+// testNamespaces exercises namespaced views against any cache
+func testNamespaces(t *testing.T, c *cache.Cache) {
+	ctx := context.Background()
+
+	orders := c.Namespace("orders")
+	users := c.Namespace("users")
+	archived := orders.Namespace("archived")
+
+	orders.Set(ctx, "1", "order")
+	users.Set(ctx, "1", "user")
+	archived.Set(ctx, "1", "archived order")
+	c.Set(ctx, "orders-total", "root")
+
+	// Keys are isolated between namespaces
+	value, err := orders.Get(ctx, "1")
+	if err != nil || value != "order" {
+		t.Errorf("Expected order, got %v (%v)", value, err)
+	}
+	value, err = c.Get(ctx, "users:1")
+	if err != nil || value != "user" {
+		t.Errorf("Expected user under prefixed key, got %v (%v)", value, err)
+	}
+	if archived.Prefix() != "orders:archived:" {
+		t.Errorf("Expected nested prefix, got %q", archived.Prefix())
+	}
+
+	// Clearing a namespace only removes its own keys, including nested ones
+	if err := orders.Clear(ctx); err != nil {
+		t.Fatalf("Namespaced Clear failed: %v", err)
+	}
+	if orders.Has(ctx, "1") || archived.Has(ctx, "1") {
+		t.Error("Expected namespace to be cleared")
+	}
+	if !users.Has(ctx, "1") || !c.Has(ctx, "orders-total") {
+		t.Error("Expected keys outside the namespace to survive")
+	}
+
+	// Tags are scoped to the namespace
+	orders.SetWithTags(ctx, "2", "order", time.Minute, "customer:1")
+	users.SetWithTags(ctx, "2", "user", time.Minute, "customer:1")
+	if err := orders.InvalidateTags(ctx, "customer:1"); err != nil {
+		t.Fatalf("InvalidateTags failed: %v", err)
+	}
+	if orders.Has(ctx, "2") || !users.Has(ctx, "2") {
+		t.Error("Expected only the namespaced tag to be invalidated")
+	}
+
+	// Closing a view leaves the parent open
+	users.Close()
+	if !c.Has(ctx, "users:1") {
+		t.Error("Expected parent to keep working after closing a view")
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	configs := map[string]*cache.Config{
+		"Memory":  cache.DefaultConfig(),
+		"Sharded": cache.DefaultConfig().WithShards(4),
+		"Wrapped": cache.DefaultConfig().WithCompression(cache.CompressionFlate, 1),
+	}
+
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			c, err := cache.New(config)
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+			defer c.Close()
+
+			testNamespaces(t, c)
+		})
+	}
+}
+
+func TestNamespaceRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL not set")
+	}
+
+	ctx := context.Background()
+	c, err := cache.New(&cache.Config{
+		Backend:    cache.BackendRedis,
+		RedisURL:   redisURL,
+		DefaultTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	root := c.Namespace("go-cache-test")
+	testNamespaces(t, root)
+
+	// Glob characters in a namespace match literally
+	star := root.Namespace("*")
+	star.Set(ctx, "key", "value")
+	root.Set(ctx, "other", "value")
+	if err := star.Clear(ctx); err != nil {
+		t.Fatalf("Namespaced Clear failed: %v", err)
+	}
+	if star.Has(ctx, "key") || !root.Has(ctx, "other") {
+		t.Error("Expected only the literal namespace to be cleared")
+	}
+	root.Clear(ctx)
+
+	// Flushing the database requires an explicit opt-in
+	if err := c.Clear(ctx); !errors.Is(err, cache.ErrFlushDisabled) {
+		t.Errorf("Expected ErrFlushDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultNegativeTTL is how long not-found results are cached when Config.NegativeTTL is not set
+const defaultNegativeTTL = time.Minute
+
+// negativeHeader is the whole value stored for a key known not to exist
+const negativeHeader byte = 0x8b
+
+var (
+	// ErrNotExist is returned by fetchers to report that the requested data
+	// does not exist. GetOrSet caches the result for Config.NegativeTTL.
+	ErrNotExist = errors.New("does not exist")
+
+	// ErrNegativeCached is returned for keys cached as not existing.
+	// It wraps ErrNotExist, so errors.Is(err, ErrNotExist) holds as well.
+	ErrNegativeCached = fmt.Errorf("%w (cached)", ErrNotExist)
+)
+
+// negativeMarker is the value stored for keys known not to exist
+var negativeMarker = []byte{negativeHeader}
+
+// setNegative stores the negative marker for a key. The marker is internal,
+// so it is not counted as a set and OnSet callbacks are not called.
+func (c *Cache) setNegative(ctx context.Context, key string) {
+	defer c.stats.observe(OpSet, time.Now())
+	if err := c.store.Set(ctx, key, negativeMarker, c.negativeTTL); err != nil {
+		c.events.failed(ctx, OpSet, c.prefix+key, err)
+		return
+	}
+	c.refresh.forget(c.prefix + key)
+}
+
+// isNegative reports whether a stored value marks a key as not existing
+func isNegative(value interface{}) bool {
+	data, ok := asBytes(value)
+	return ok && len(data) == 1 && data[0] == negativeHeader
+}
@@ -0,0 +1,93 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+func TestNegativeCaching(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig().WithNegativeTTL(50 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	calls := 0
+	fetcher := func() (interface{}, error) {
+		calls++
+		return nil, fmt.Errorf("user 42: %w", cache.ErrNotExist)
+	}
+
+	// The fetcher's own error is returned the first time
+	if _, err := c.GetOrSet(ctx, "user:42", fetcher, time.Minute); !errors.Is(err, cache.ErrNotExist) || errors.Is(err, cache.ErrNegativeCached) {
+		t.Fatalf("Expected fetcher error, got %v", err)
+	}
+
+	if _, err := c.GetOrSet(ctx, "user:42", fetcher, time.Minute); !errors.Is(err, cache.ErrNegativeCached) {
+		t.Errorf("Expected ErrNegativeCached from GetOrSet, got %v", err)
+	}
+	if _, err := c.Get(ctx, "user:42"); !errors.Is(err, cache.ErrNegativeCached) || !errors.Is(err, cache.ErrNotExist) {
+		t.Errorf("Expected ErrNegativeCached from Get, got %v", err)
+	}
+	if c.Has(ctx, "user:42") {
+		t.Error("Expected Has to agree with Get for a negative entry")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 fetcher call, got %d", calls)
+	}
+
+	// Once the negative TTL passes the fetcher runs again
+	time.Sleep(100 * time.Millisecond)
+	value, err := c.GetOrSet(ctx, "user:42", func() (interface{}, error) {
+		return "John", nil
+	}, time.Minute)
+	if err != nil || value != "John" {
+		t.Errorf("Expected fetched value after negative TTL, got %v, %v", value, err)
+	}
+
+	// Other fetcher errors are not cached
+	c.GetOrSet(ctx, "flaky", func() (interface{}, error) {
+		return nil, errors.New("timeout")
+	}, time.Minute)
+	if _, err := c.Get(ctx, "flaky"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected other errors not to be cached, got %v", err)
+	}
+}
+
+func TestNegativeCachingStats(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	hits := 0
+	c.OnHit(func(ctx context.Context, key string) { hits++ })
+
+	// Reads of keys cached as not existing are misses
+	c.GetOrSet(ctx, "gone", func() (interface{}, error) {
+		return nil, cache.ErrNotExist
+	}, time.Minute)
+	c.Get(ctx, "gone")
+
+	if stats := c.Stats(); stats.Hits != 0 || stats.Misses != 2 || hits != 0 {
+		t.Errorf("Expected 0 hits and 2 misses, got %d hits (%d callbacks) and %d misses", stats.Hits, hits, stats.Misses)
+	}
+
+	// User bytes equal to the marker are values, not negative entries
+	c.Set(ctx, "marker", []byte{0x8b})
+	value, err := c.Get(ctx, "marker")
+	if got, ok := value.([]byte); err != nil || !ok || !bytes.Equal(got, []byte{0x8b}) {
+		t.Errorf("Expected marker bytes to round-trip, got %v (%v)", value, err)
+	}
+}
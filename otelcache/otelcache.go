@@ -0,0 +1,282 @@
+// Package otelcache emits OpenTelemetry spans for go-cache operations
+package otelcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer used by this package
+const instrumentationName = "github.com/OkanUysal/go-cache/otelcache"
+
+// Span attribute keys
+const (
+	BackendKey   = attribute.Key("cache.backend")
+	KeyKey       = attribute.Key("cache.key")
+	KeyHashKey   = attribute.Key("cache.key_hash")
+	HitKey       = attribute.Key("cache.hit")
+	ValueSizeKey = attribute.Key("cache.value_size")
+	TagsKey      = attribute.Key("cache.tags")
+)
+
+// Options configures the instrumentation
+type Options struct {
+	// TracerProvider creates the tracer
+	// Default: otel.GetTracerProvider()
+	TracerProvider trace.TracerProvider
+
+	// Backend is recorded on every span, e.g. "redis"
+	// Default: "" (not recorded by Store; GetOrSet uses the cache backend)
+	Backend string
+
+	// HashKeys records a SHA-256 prefix of each key instead of the key itself,
+	// for keys that contain personal data
+	// Default: false
+	HashKeys bool
+}
+
+// tracer creates the tracer described by the options
+func (o Options) tracer() trace.Tracer {
+	provider := o.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(instrumentationName)
+}
+
+// keyAttribute returns the attribute recording key
+func (o Options) keyAttribute(key string) attribute.KeyValue {
+	if o.HashKeys {
+		sum := sha256.Sum256([]byte(key))
+		return KeyHashKey.String(hex.EncodeToString(sum[:8]))
+	}
+	return KeyKey.String(key)
+}
+
+// start starts a client span for a cache operation
+func (o Options) start(ctx context.Context, tracer trace.Tracer, op, key string, backend string) (context.Context, trace.Span) {
+	attrs := make([]attribute.KeyValue, 0, 2)
+	if backend != "" {
+		attrs = append(attrs, BackendKey.String(backend))
+	}
+	if key != "" {
+		attrs = append(attrs, o.keyAttribute(key))
+	}
+
+	return tracer.Start(ctx, "cache."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...))
+}
+
+// end records err, if any, and ends the span
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// valueSize returns the size of strings and byte slices
+func valueSize(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		return len(v), true
+	case []byte:
+		return len(v), true
+	}
+	return 0, false
+}
+
+// Store wraps a cache.Store and emits a span for every operation
+type Store struct {
+	next   cache.Store
+	tracer trace.Tracer
+	opts   Options
+}
+
+// NewStore wraps next. Use it with cache.NewWithStore to trace a Cache.
+func NewStore(next cache.Store, opts Options) *Store {
+	return &Store{
+		next:   next,
+		tracer: opts.tracer(),
+		opts:   opts,
+	}
+}
+
+// start starts a span for an operation on key
+func (s *Store) start(ctx context.Context, op, key string) (context.Context, trace.Span) {
+	return s.opts.start(ctx, s.tracer, op, key, s.opts.Backend)
+}
+
+// Get retrieves a value; misses are recorded as cache.hit=false rather than errors
+func (s *Store) Get(ctx context.Context, key string) (interface{}, error) {
+	ctx, span := s.start(ctx, "get", key)
+
+	value, err := s.next.Get(ctx, key)
+	span.SetAttributes(HitKey.Bool(err == nil))
+	if size, ok := valueSize(value); ok {
+		span.SetAttributes(ValueSizeKey.Int(size))
+	}
+
+	if errors.Is(err, cache.ErrNotFound) {
+		end(span, nil)
+	} else {
+		end(span, err)
+	}
+	return value, err
+}
+
+// Set stores a value
+func (s *Store) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	ctx, span := s.start(ctx, "set", key)
+	if size, ok := valueSize(value); ok {
+		span.SetAttributes(ValueSizeKey.Int(size))
+	}
+
+	err := s.next.Set(ctx, key, value, ttl)
+	end(span, err)
+	return err
+}
+
+// SetWithTags stores a tagged value if the wrapped store supports tags
+func (s *Store) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	tagged, ok := s.next.(cache.TagStore)
+	if !ok {
+		return cache.ErrTagsNotSupported
+	}
+
+	ctx, span := s.start(ctx, "set", key)
+	span.SetAttributes(TagsKey.StringSlice(tags))
+	if size, ok := valueSize(value); ok {
+		span.SetAttributes(ValueSizeKey.Int(size))
+	}
+
+	err := tagged.SetWithTags(ctx, key, value, ttl, tags)
+	end(span, err)
+	return err
+}
+
+// InvalidateTags removes tagged keys if the wrapped store supports tags
+func (s *Store) InvalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	tagged, ok := s.next.(cache.TagStore)
+	if !ok {
+		return nil, cache.ErrTagsNotSupported
+	}
+
+	ctx, span := s.start(ctx, "invalidate_tags", "")
+	span.SetAttributes(TagsKey.StringSlice(tags))
+
+	removed, err := tagged.InvalidateTags(ctx, tags...)
+	end(span, err)
+	return removed, err
+}
+
+// Delete removes a value
+func (s *Store) Delete(ctx context.Context, key string) error {
+	ctx, span := s.start(ctx, "delete", key)
+
+	err := s.next.Delete(ctx, key)
+	end(span, err)
+	return err
+}
+
+// DeletePrefix removes keys starting with prefix if the wrapped store supports it
+func (s *Store) DeletePrefix(ctx context.Context, prefix string) error {
+	deleter, ok := s.next.(cache.PrefixDeleter)
+	if !ok {
+		return cache.ErrPrefixNotSupported
+	}
+
+	ctx, span := s.start(ctx, "delete_prefix", prefix)
+
+	err := deleter.DeletePrefix(ctx, prefix)
+	end(span, err)
+	return err
+}
+
+// Has checks if a key exists
+func (s *Store) Has(ctx context.Context, key string) bool {
+	ctx, span := s.start(ctx, "has", key)
+
+	found := s.next.Has(ctx, key)
+	span.SetAttributes(HitKey.Bool(found))
+	end(span, nil)
+	return found
+}
+
+// Increment increments a numeric value
+func (s *Store) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	ctx, span := s.start(ctx, "increment", key)
+
+	value, err := s.next.Increment(ctx, key, delta)
+	end(span, err)
+	return value, err
+}
+
+// Decrement decrements a numeric value
+func (s *Store) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	ctx, span := s.start(ctx, "decrement", key)
+
+	value, err := s.next.Decrement(ctx, key, delta)
+	end(span, err)
+	return value, err
+}
+
+// Clear removes all entries
+func (s *Store) Clear(ctx context.Context) error {
+	ctx, span := s.start(ctx, "clear", "")
+
+	err := s.next.Clear(ctx)
+	end(span, err)
+	return err
+}
+
+// Close closes the wrapped store
+func (s *Store) Close() error {
+	return s.next.Close()
+}
+
+// Unwrap returns the wrapped store
+func (s *Store) Unwrap() cache.Store {
+	return s.next
+}
+
+// GetOrSet calls c.GetOrSet inside a "cache.get_or_set" span. On a miss the
+// fetcher runs in a "cache.fetch" child span and receives its context, so
+// work done by the fetcher is traced beneath it. Like the fetcher call itself,
+// that context is not cancelled when ctx is.
+func GetOrSet(ctx context.Context, c *cache.Cache, key string, fetcher func(ctx context.Context) (interface{}, error), ttl time.Duration, opts Options) (interface{}, error) {
+	tracer := opts.tracer()
+
+	backend := opts.Backend
+	if backend == "" {
+		backend = string(c.Backend())
+	}
+
+	ctx, span := opts.start(ctx, tracer, "get_or_set", key, backend)
+
+	var fetched atomic.Bool
+	value, err := c.GetOrSet(ctx, key, func() (interface{}, error) {
+		fetched.Store(true)
+
+		fetchCtx, fetchSpan := tracer.Start(context.WithoutCancel(ctx), "cache.fetch")
+		value, err := fetcher(fetchCtx)
+		end(fetchSpan, err)
+		return value, err
+	}, ttl)
+
+	span.SetAttributes(HitKey.Bool(err == nil && !fetched.Load()))
+	end(span, err)
+	return value, err
+}
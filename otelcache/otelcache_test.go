@@ -0,0 +1,143 @@
+package otelcache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+	"github.com/OkanUysal/go-cache/otelcache"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// attributes returns the attributes of a span as a map
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestStoreSpans(t *testing.T) {
+	ctx := context.Background()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	store := otelcache.NewStore(cache.NewMemoryStore(time.Minute), otelcache.Options{
+		TracerProvider: provider,
+		Backend:        "memory",
+	})
+	c := cache.NewWithStore(store, cache.DefaultConfig())
+	defer c.Close()
+
+	c.Set(ctx, "user:1", "John")
+	c.Get(ctx, "user:1")
+	c.Get(ctx, "missing")
+	c.Increment(ctx, "visits", 1)
+	c.Delete(ctx, "user:1")
+
+	spans := recorder.Ended()
+	names := []string{"cache.set", "cache.get", "cache.get", "cache.increment", "cache.delete"}
+	if len(spans) != len(names) {
+		t.Fatalf("Expected %d spans, got %d", len(names), len(spans))
+	}
+	for i, name := range names {
+		if spans[i].Name() != name {
+			t.Errorf("Expected span %s, got %s", name, spans[i].Name())
+		}
+	}
+
+	set := attributes(spans[0])
+	if set[otelcache.KeyKey].AsString() != "user:1" || set[otelcache.ValueSizeKey].AsInt64() != 4 ||
+		set[otelcache.BackendKey].AsString() != "memory" {
+		t.Errorf("Unexpected set attributes %v", set)
+	}
+
+	if !attributes(spans[1])[otelcache.HitKey].AsBool() {
+		t.Error("Expected hit on first get")
+	}
+
+	// A miss is not an error
+	if attributes(spans[2])[otelcache.HitKey].AsBool() || spans[2].Status().Code == codes.Error {
+		t.Error("Expected miss without error on second get")
+	}
+}
+
+func TestHashedKeys(t *testing.T) {
+	ctx := context.Background()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	store := otelcache.NewStore(cache.NewMemoryStore(time.Minute), otelcache.Options{
+		TracerProvider: provider,
+		HashKeys:       true,
+	})
+	defer store.Close()
+
+	store.Set(ctx, "email:john@example.com", "1", 0)
+
+	attrs := attributes(recorder.Ended()[0])
+	if _, found := attrs[otelcache.KeyKey]; found {
+		t.Error("Expected raw key not to be recorded")
+	}
+	if hash := attrs[otelcache.KeyHashKey].AsString(); len(hash) != 16 {
+		t.Errorf("Expected 16 character key hash, got %q", hash)
+	}
+}
+
+func TestGetOrSetSpans(t *testing.T) {
+	ctx := context.Background()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opts := otelcache.Options{TracerProvider: provider}
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	fetcher := func(ctx context.Context) (interface{}, error) {
+		return "value", nil
+	}
+
+	otelcache.GetOrSet(ctx, c, "key", fetcher, time.Minute, opts)
+	otelcache.GetOrSet(ctx, c, "key", fetcher, time.Minute, opts)
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	fetch, miss, hit := spans[0], spans[1], spans[2]
+	if fetch.Name() != "cache.fetch" || fetch.Parent().SpanID() != miss.SpanContext().SpanID() {
+		t.Error("Expected fetch span to be a child of the get_or_set span")
+	}
+	if attributes(miss)[otelcache.HitKey].AsBool() || !attributes(hit)[otelcache.HitKey].AsBool() {
+		t.Error("Expected a miss followed by a hit")
+	}
+	if attributes(hit)[otelcache.BackendKey].AsString() != "memory" {
+		t.Error("Expected backend attribute from the cache")
+	}
+
+	// Fetcher errors are recorded on both spans
+	recorder = tracetest.NewSpanRecorder()
+	opts.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otelcache.GetOrSet(ctx, c, "failing", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("boom")
+	}, time.Minute, opts)
+
+	for _, span := range recorder.Ended() {
+		if span.Status().Code != codes.Error {
+			t.Errorf("Expected %s to record the error", span.Name())
+		}
+	}
+}
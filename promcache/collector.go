@@ -0,0 +1,185 @@
+// Package promcache exports go-cache statistics as Prometheus metrics
+package promcache
+
+import (
+	"github.com/OkanUysal/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Options configures a Collector
+type Options struct {
+	// Namespace prefixes every metric name
+	// Default: "cache"
+	Namespace string
+
+	// ConstLabels are added to every metric, e.g. to tell several caches apart
+	// Default: nil
+	ConstLabels prometheus.Labels
+}
+
+// Collector is a prometheus.Collector for the statistics of a Cache
+type Collector struct {
+	cache *cache.Cache
+
+	hits         *prometheus.Desc
+	misses       *prometheus.Desc
+	sets         *prometheus.Desc
+	deletes      *prometheus.Desc
+	evictions    *prometheus.Desc
+	expirations  *prometheus.Desc
+	loaderCalls  *prometheus.Desc
+	loaderErrors *prometheus.Desc
+	hitRatio     *prometheus.Desc
+	latency      *prometheus.Desc
+
+	items *prometheus.Desc
+	bytes *prometheus.Desc
+
+	poolHits       *prometheus.Desc
+	poolMisses     *prometheus.Desc
+	poolTimeouts   *prometheus.Desc
+	poolTotalConns *prometheus.Desc
+	poolIdleConns  *prometheus.Desc
+	poolStaleConns *prometheus.Desc
+}
+
+// NewCollector creates a collector for c. Register it with a prometheus.Registerer.
+func NewCollector(c *cache.Cache, opts Options) *Collector {
+	if opts.Namespace == "" {
+		opts.Namespace = "cache"
+	}
+
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(opts.Namespace, "", name), help, labels, opts.ConstLabels)
+	}
+
+	return &Collector{
+		cache: c,
+
+		hits:         desc("hits_total", "Number of reads that found a value.", "backend"),
+		misses:       desc("misses_total", "Number of reads that found nothing or failed.", "backend"),
+		sets:         desc("sets_total", "Number of successful writes.", "backend"),
+		deletes:      desc("deletes_total", "Number of successful deletes.", "backend"),
+		evictions:    desc("evictions_total", "Number of entries evicted by the store.", "backend"),
+		expirations:  desc("expirations_total", "Number of expired entries removed by the store.", "backend"),
+		loaderCalls:  desc("loader_calls_total", "Number of GetOrSet fetcher calls.", "backend"),
+		loaderErrors: desc("loader_errors_total", "Number of GetOrSet fetcher calls that failed.", "backend"),
+		hitRatio:     desc("hit_ratio", "Hits divided by reads.", "backend"),
+		latency:      desc("operation_duration_seconds", "Latency of cache operations.", "operation", "backend"),
+
+		items: desc("items", "Number of entries held in memory.", "backend"),
+		bytes: desc("bytes", "Approximate size of entries held in memory.", "backend"),
+
+		poolHits:       desc("redis_pool_hits_total", "Number of times a free connection was found in the pool.", "backend"),
+		poolMisses:     desc("redis_pool_misses_total", "Number of times a free connection was not found in the pool.", "backend"),
+		poolTimeouts:   desc("redis_pool_timeouts_total", "Number of times a wait for a connection timed out.", "backend"),
+		poolTotalConns: desc("redis_pool_connections", "Number of connections in the pool.", "backend"),
+		poolIdleConns:  desc("redis_pool_idle_connections", "Number of idle connections in the pool.", "backend"),
+		poolStaleConns: desc("redis_pool_stale_connections_total", "Number of stale connections removed from the pool.", "backend"),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.hits, c.misses, c.sets, c.deletes, c.evictions, c.expirations,
+		c.loaderCalls, c.loaderErrors, c.hitRatio, c.latency,
+		c.items, c.bytes,
+		c.poolHits, c.poolMisses, c.poolTimeouts, c.poolTotalConns, c.poolIdleConns, c.poolStaleConns,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	backend := string(c.cache.Backend())
+	stats := c.cache.Stats()
+
+	counter := func(desc *prometheus.Desc, value uint64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), backend)
+	}
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, backend)
+	}
+
+	counter(c.hits, stats.Hits)
+	counter(c.misses, stats.Misses)
+	counter(c.sets, stats.Sets)
+	counter(c.deletes, stats.Deletes)
+	counter(c.evictions, stats.Evictions)
+	counter(c.expirations, stats.Expirations)
+	counter(c.loaderCalls, stats.LoaderCalls)
+	counter(c.loaderErrors, stats.LoaderErrors)
+	gauge(c.hitRatio, stats.HitRatio())
+
+	for op, histogram := range stats.Latencies {
+		// Prometheus buckets are cumulative
+		buckets := make(map[float64]uint64, len(histogram.Buckets))
+		var cumulative uint64
+		for i, bound := range histogram.Buckets {
+			cumulative += histogram.Counts[i]
+			buckets[bound.Seconds()] = cumulative
+		}
+
+		ch <- prometheus.MustNewConstHistogram(c.latency,
+			histogram.Count, histogram.Sum.Seconds(), buckets, string(op), backend)
+	}
+
+	if memory := findMemory(c.cache.GetStore()); memory != nil {
+		gauge(c.items, float64(memory.Len()))
+		gauge(c.bytes, float64(memory.Bytes()))
+	}
+
+	if redisStore := findRedis(c.cache.GetStore()); redisStore != nil {
+		pool := redisStore.UniversalClient().PoolStats()
+		counter(c.poolHits, uint64(pool.Hits))
+		counter(c.poolMisses, uint64(pool.Misses))
+		counter(c.poolTimeouts, uint64(pool.Timeouts))
+		gauge(c.poolTotalConns, float64(pool.TotalConns))
+		gauge(c.poolIdleConns, float64(pool.IdleConns))
+		counter(c.poolStaleConns, uint64(pool.StaleConns))
+	}
+}
+
+// memoryStore is implemented by MemoryStore and ShardedMemoryStore
+type memoryStore interface {
+	Len() int
+	Bytes() int64
+}
+
+// findMemory returns the in-memory store behind store wrappers, or the
+// local tier of a tiered store
+func findMemory(store cache.Store) memoryStore {
+	for store != nil {
+		switch s := store.(type) {
+		case memoryStore:
+			return s
+		case *cache.TieredStore:
+			store = s.L1()
+		case interface{ Unwrap() cache.Store }:
+			store = s.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// findRedis returns the RedisStore behind store wrappers, or the shared
+// tier of a tiered store
+func findRedis(store cache.Store) *cache.RedisStore {
+	for store != nil {
+		switch s := store.(type) {
+		case *cache.RedisStore:
+			return s
+		case *cache.TieredStore:
+			store = s.L2()
+		case interface{ Unwrap() cache.Store }:
+			store = s.Unwrap()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package promcache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+	"github.com/OkanUysal/go-cache/promcache"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig().WithShards(2))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	registry := prometheus.NewRegistry()
+	collector := promcache.NewCollector(c, promcache.Options{
+		Namespace:   "app_cache",
+		ConstLabels: prometheus.Labels{"cache": "users"},
+	})
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	c.Set(ctx, "a", "value")
+	c.Get(ctx, "a")
+	c.Get(ctx, "a")
+	c.Get(ctx, "missing")
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+
+	metrics := make(map[string]*dto.MetricFamily)
+	for _, family := range families {
+		metrics[family.GetName()] = family
+	}
+
+	value := func(name string) float64 {
+		family, found := metrics[name]
+		if !found {
+			t.Fatalf("Metric %s not found", name)
+		}
+		metric := family.GetMetric()[0]
+		if metric.GetCounter() != nil {
+			return metric.GetCounter().GetValue()
+		}
+		return metric.GetGauge().GetValue()
+	}
+
+	if hits := value("app_cache_hits_total"); hits != 2 {
+		t.Errorf("Expected 2 hits, got %v", hits)
+	}
+	if misses := value("app_cache_misses_total"); misses != 1 {
+		t.Errorf("Expected 1 miss, got %v", misses)
+	}
+	if items := value("app_cache_items"); items != 1 {
+		t.Errorf("Expected 1 item, got %v", items)
+	}
+	if ratio := value("app_cache_hit_ratio"); ratio < 0.66 || ratio > 0.67 {
+		t.Errorf("Expected hit ratio 2/3, got %v", ratio)
+	}
+	if _, found := metrics["app_cache_redis_pool_connections"]; found {
+		t.Error("Expected no Redis pool metrics for the memory backend")
+	}
+
+	// Latencies are exported as one histogram per operation
+	latency := metrics["app_cache_operation_duration_seconds"]
+	if latency == nil {
+		t.Fatal("Latency histogram not found")
+	}
+	for _, metric := range latency.GetMetric() {
+		labels := make(map[string]string)
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		if labels["backend"] != "memory" || labels["cache"] != "users" {
+			t.Errorf("Unexpected labels %v", labels)
+		}
+
+		histogram := metric.GetHistogram()
+		if labels["operation"] == string(cache.OpGet) && histogram.GetSampleCount() != 3 {
+			t.Errorf("Expected 3 gets, got %d", histogram.GetSampleCount())
+		}
+
+		// Buckets are cumulative
+		buckets := histogram.GetBucket()
+		last := buckets[len(buckets)-1]
+		if last.GetUpperBound() != time.Second.Seconds() || last.GetCumulativeCount() > histogram.GetSampleCount() {
+			t.Errorf("Unexpected last bucket %v", last)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	// ErrInvalidRateLimit is returned for limits without a positive Limit and
+	// Window, or requests for more than Limit at once
+	ErrInvalidRateLimit = errors.New("invalid rate limit")
+
+	// ErrRateLimitNotSupported is returned when the store does not implement RateLimitStore
+	ErrRateLimitNotSupported = errors.New("store does not support rate limiting")
+)
+
+// RateLimitAlgorithm selects how requests are counted
+type RateLimitAlgorithm string
+
+const (
+	// FixedWindow allows Limit requests per Window, starting with the first request.
+	// Cheapest, but allows up to twice the limit around a window boundary
+	FixedWindow RateLimitAlgorithm = "fixed_window"
+
+	// SlidingWindowLog records every request and allows Limit in any Window.
+	// Exact, but stores one entry per request
+	SlidingWindowLog RateLimitAlgorithm = "sliding_log"
+
+	// SlidingWindowCounter weights the previous window's count by how much of
+	// it still overlaps the sliding window. Close to exact with constant state
+	SlidingWindowCounter RateLimitAlgorithm = "sliding_counter"
+
+	// TokenBucket allows bursts of up to Limit requests, refilled at Limit per Window
+	TokenBucket RateLimitAlgorithm = "token_bucket"
+)
+
+// RateLimit describes a limit of Limit requests per Window
+type RateLimit struct {
+	// Algorithm selects how requests are counted
+	// Default: FixedWindow
+	Algorithm RateLimitAlgorithm
+
+	// Limit is the number of requests allowed per Window, or the bucket size
+	Limit int64
+
+	// Window is the period of the limit, or the time to refill an empty bucket.
+	// Redis counts it in milliseconds for FixedWindow and microseconds otherwise
+	Window time.Duration
+}
+
+// RateLimitResult reports the outcome of a rate limited request
+type RateLimitResult struct {
+	// Allowed reports whether the request may proceed
+	Allowed bool
+
+	// Limit is the configured limit
+	Limit int64
+
+	// Remaining is how many more requests are currently allowed
+	Remaining int64
+
+	// RetryAfter is how long to wait before the request would be allowed; 0 if it was
+	RetryAfter time.Duration
+}
+
+// RateLimitStore is implemented by stores that can apply rate limits atomically
+type RateLimitStore interface {
+	// AllowN records n requests against key if limit allows all of them
+	AllowN(ctx context.Context, key string, limit RateLimit, n int64) (RateLimitResult, error)
+}
+
+// RateLimiter applies a rate limit per key using the backend of a cache.
+// Limits are shared by every instance using the same Redis server.
+type RateLimiter struct {
+	store  RateLimitStore
+	prefix string
+	limit  RateLimit
+}
+
+// RateLimiter returns a RateLimiter applying limit. On a namespaced cache,
+// keys are scoped to the namespace. Its methods fail with ErrInvalidRateLimit
+// for invalid limits and ErrRateLimitNotSupported if the store does not
+// implement RateLimitStore.
+func (c *Cache) RateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Algorithm == "" {
+		limit.Algorithm = FixedWindow
+	}
+
+	store, _ := findStore[RateLimitStore](c.store)
+	return &RateLimiter{
+		store:  store,
+		prefix: c.prefix,
+		limit:  limit,
+	}
+}
+
+// Allow records one request against key
+func (l *RateLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// AllowN records n requests against key if all of them are allowed
+func (l *RateLimiter) AllowN(ctx context.Context, key string, n int64) (RateLimitResult, error) {
+	if l.limit.Limit <= 0 || l.limit.Window <= 0 || n <= 0 || n > l.limit.Limit {
+		return RateLimitResult{}, ErrInvalidRateLimit
+	}
+	if l.store == nil {
+		return RateLimitResult{}, ErrRateLimitNotSupported
+	}
+	return l.store.AllowN(ctx, l.prefix+key, l.limit, n)
+}
+
+// rateLimitKey returns the key holding the state of a rate limit
+func rateLimitKey(key string, limit RateLimit) string {
+	return rateLimitKeyPrefix + string(limit.Algorithm) + ":" + key
+}
+
+// rateLimitKeyPrefix prefixes keys holding rate limit state
+const rateLimitKeyPrefix = "__ratelimit:"
+
+// rateLimitState is the state of one rate limited key in a MemoryStore.
+// Times are Unix nanoseconds.
+type rateLimitState struct {
+	count   int64   // requests in the current window
+	prev    int64   // requests in the previous window (sliding counter)
+	window  int64   // end of the window (fixed), or window index (sliding counter)
+	tokens  float64 // tokens left (token bucket)
+	updated int64   // last refill (token bucket)
+	log     []int64 // request times, oldest first (sliding log)
+	expires int64   // when the state no longer affects any request
+}
+
+// apply records n requests at now against the state. Denied requests leave
+// the state unchanged apart from discarding what has expired.
+func (l RateLimit) apply(s *rateLimitState, now, n int64) RateLimitResult {
+	switch l.Algorithm {
+	case SlidingWindowLog:
+		return l.slidingLog(s, now, n)
+	case SlidingWindowCounter:
+		return l.slidingCounter(s, now, n)
+	case TokenBucket:
+		return l.tokenBucket(s, now, n)
+	default:
+		return l.fixedWindow(s, now, n)
+	}
+}
+
+// denied returns a result for a denied request
+func (l RateLimit) denied(remaining int64, retryAfter int64) RateLimitResult {
+	if remaining < 0 {
+		remaining = 0
+	}
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	return RateLimitResult{Limit: l.Limit, Remaining: remaining, RetryAfter: time.Duration(retryAfter)}
+}
+
+// allowed returns a result for an allowed request
+func (l RateLimit) allowed(remaining int64) RateLimitResult {
+	return RateLimitResult{Allowed: true, Limit: l.Limit, Remaining: remaining}
+}
+
+// fixedWindow counts requests in windows starting with the first request
+func (l RateLimit) fixedWindow(s *rateLimitState, now, n int64) RateLimitResult {
+	if now >= s.window {
+		s.count = 0
+		s.window = now + int64(l.Window)
+	}
+
+	if s.count+n > l.Limit {
+		return l.denied(l.Limit-s.count, s.window-now)
+	}
+
+	s.count += n
+	s.expires = s.window
+	return l.allowed(l.Limit - s.count)
+}
+
+// slidingLog keeps the time of every request in the last window
+func (l RateLimit) slidingLog(s *rateLimitState, now, n int64) RateLimitResult {
+	window := int64(l.Window)
+
+	expired := 0
+	for expired < len(s.log) && s.log[expired] <= now-window {
+		expired++
+	}
+	s.log = s.log[expired:]
+
+	count := int64(len(s.log))
+	if count+n > l.Limit {
+		// Wait until enough of the oldest requests leave the window
+		oldest := s.log[count+n-l.Limit-1]
+		return l.denied(l.Limit-count, oldest+window-now)
+	}
+
+	for i := int64(0); i < n; i++ {
+		s.log = append(s.log, now)
+	}
+	s.expires = now + window
+	return l.allowed(l.Limit - count - n)
+}
+
+// slidingCounter estimates the requests in the sliding window from the
+// counts of the current and previous fixed windows
+func (l RateLimit) slidingCounter(s *rateLimitState, now, n int64) RateLimitResult {
+	window := int64(l.Window)
+	index := now / window
+
+	if s.window != index {
+		if s.window == index-1 {
+			s.prev = s.count
+		} else {
+			s.prev = 0
+		}
+		s.count = 0
+		s.window = index
+	}
+
+	elapsed := now - index*window
+	estimate := float64(s.prev)*float64(window-elapsed)/float64(window) + float64(s.count)
+	if estimate+float64(n) > float64(l.Limit) {
+		// The previous window's weight shrinks linearly as it slides out; if the
+		// current window is too full, wait for it to become the previous one
+		var retry int64
+		if free := float64(l.Limit - s.count - n); free >= 0 {
+			retry = int64(math.Ceil(float64(window)*(1-free/float64(s.prev)))) - elapsed
+		} else {
+			free = float64(l.Limit - n)
+			retry = window - elapsed + int64(math.Ceil(float64(window)*(1-free/float64(s.count))))
+		}
+		return l.denied(int64(float64(l.Limit)-estimate), retry)
+	}
+
+	s.count += n
+	s.expires = (index + 2) * window
+	return l.allowed(int64(float64(l.Limit) - estimate - float64(n)))
+}
+
+// tokenBucket refills Limit tokens per Window and takes n per request
+func (l RateLimit) tokenBucket(s *rateLimitState, now, n int64) RateLimitResult {
+	rate := float64(l.Limit) / float64(l.Window)
+
+	tokens := float64(l.Limit)
+	if s.updated != 0 {
+		tokens = math.Min(tokens, s.tokens+float64(now-s.updated)*rate)
+	}
+
+	if tokens < float64(n) {
+		return l.denied(int64(tokens), int64(math.Ceil((float64(n)-tokens)/rate)))
+	}
+
+	s.tokens = tokens - float64(n)
+	s.updated = now
+	s.expires = now + int64(math.Ceil((float64(l.Limit)-s.tokens)/rate))
+	return l.allowed(int64(s.tokens))
+}
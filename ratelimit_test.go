@@ -0,0 +1,127 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+var rateLimitAlgorithms = []cache.RateLimitAlgorithm{
+	cache.FixedWindow,
+	cache.SlidingWindowLog,
+	cache.SlidingWindowCounter,
+	cache.TokenBucket,
+}
+
+// testRateLimiter checks that every algorithm allows a burst of Limit
+// requests, denies the next one with a retry hint and recovers in time
+func testRateLimiter(t *testing.T, c *cache.Cache) {
+	ctx := context.Background()
+
+	for _, algorithm := range rateLimitAlgorithms {
+		t.Run(string(algorithm), func(t *testing.T) {
+			limiter := c.RateLimiter(cache.RateLimit{
+				Algorithm: algorithm,
+				Limit:     3,
+				Window:    100 * time.Millisecond,
+			})
+
+			for i := int64(0); i < 3; i++ {
+				result, err := limiter.Allow(ctx, "user:1")
+				if err != nil {
+					t.Fatalf("Allow failed: %v", err)
+				}
+				if !result.Allowed || result.Remaining != 2-i {
+					t.Errorf("Expected request %d allowed with %d remaining, got %+v", i, 2-i, result)
+				}
+			}
+
+			result, err := limiter.Allow(ctx, "user:1")
+			if err != nil {
+				t.Fatalf("Allow failed: %v", err)
+			}
+			if result.Allowed || result.Remaining != 0 {
+				t.Errorf("Expected request over the limit to be denied, got %+v", result)
+			}
+			// A full sliding counter window must roll over before it frees up
+			if result.RetryAfter <= 0 || result.RetryAfter > 200*time.Millisecond {
+				t.Errorf("Expected retry after within two windows, got %v", result.RetryAfter)
+			}
+
+			// Other keys have their own quota
+			if result, _ := limiter.Allow(ctx, "user:2"); !result.Allowed {
+				t.Error("Expected another key to be allowed")
+			}
+
+			time.Sleep(result.RetryAfter + 10*time.Millisecond)
+			if result, _ := limiter.Allow(ctx, "user:1"); !result.Allowed {
+				t.Errorf("Expected request after retry-after to be allowed, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	testRateLimiter(t, c)
+
+	limiter := c.RateLimiter(cache.RateLimit{Limit: 2, Window: time.Second})
+	if _, err := limiter.AllowN(context.Background(), "user:1", 3); !errors.Is(err, cache.ErrInvalidRateLimit) {
+		t.Errorf("Expected ErrInvalidRateLimit for n over the limit, got %v", err)
+	}
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	window := 200 * time.Millisecond
+	limiter := c.RateLimiter(cache.RateLimit{Algorithm: cache.SlidingWindowCounter, Limit: 10, Window: window})
+
+	// Fill the limit at the start of a window
+	time.Sleep(window - time.Duration(time.Now().UnixNano()%int64(window)))
+	if result, _ := limiter.AllowN(ctx, "key", 10); !result.Allowed {
+		t.Fatalf("Expected full quota to be allowed, got %+v", result)
+	}
+
+	// Early in the next window most of the previous count still applies,
+	// unlike a fixed window that would allow 10 more
+	time.Sleep(window + window/4)
+	result, _ := limiter.AllowN(ctx, "key", 5)
+	if result.Allowed {
+		t.Errorf("Expected the previous window to still count, got %+v", result)
+	}
+}
+
+func TestRateLimiterRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL not set")
+	}
+
+	c, err := cache.New(&cache.Config{
+		Backend:    cache.BackendRedis,
+		RedisURL:   redisURL,
+		DefaultTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	testRateLimiter(t, c.Namespace("ratelimittest"))
+}
@@ -2,16 +2,30 @@ package cache
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-// RedisStore implements a Redis-backed cache
+// defaultPingTimeout bounds the connection test done when a store is created
+const defaultPingTimeout = 5 * time.Second
+
+// RedisStore implements a Redis-backed cache on a single node, a Sentinel
+// managed master or a Redis Cluster
 type RedisStore struct {
-	client *redis.Client
+	client     redis.UniversalClient
+	cluster    *redis.ClusterClient
+	allowFlush bool
+	counters   redisCounters
 }
 
 // NewRedisStore creates a new Redis-backed cache
@@ -21,19 +35,174 @@ func NewRedisStore(redisURL string) (*RedisStore, error) {
 		return nil, err
 	}
 
-	client := redis.NewClient(opts)
+	return NewRedisStoreWithClient(redis.NewClient(opts))
+}
+
+// NewRedisStoreWithOptions creates a Redis-backed cache with go-redis universal
+// options: a Sentinel client if MasterName is set, a Cluster client if there
+// are several addresses, and a single node client otherwise
+func NewRedisStoreWithOptions(opts *redis.UniversalOptions) (*RedisStore, error) {
+	return NewRedisStoreWithClient(redis.NewUniversalClient(opts))
+}
+
+// NewRedisStoreWithClient creates a Redis-backed cache on an existing client,
+// such as a *redis.ClusterClient created from a single seed address.
+// The client is closed together with the store.
+func NewRedisStoreWithClient(client redis.UniversalClient) (*RedisStore, error) {
+	return connectRedisStore(client, defaultPingTimeout)
+}
 
+// connectRedisStore creates a store once the client answers a ping within timeout
+func connectRedisStore(client redis.UniversalClient, timeout time.Duration) (*RedisStore, error) {
 	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, err
 	}
 
+	return newRedisStore(client), nil
+}
+
+// newRedisStore creates a store without testing the connection
+func newRedisStore(client redis.UniversalClient) *RedisStore {
+	cluster, _ := client.(*redis.ClusterClient)
 	return &RedisStore{
-		client: client,
-	}, nil
+		client:  client,
+		cluster: cluster,
+	}
+}
+
+// newRedisClient creates a client from the Redis settings of the config:
+// a Cluster client for RedisCluster or several RedisAddrs, a Sentinel client
+// for RedisMasterName, and a single node client otherwise. RedisURL supplies
+// the address if RedisAddrs is empty, and the defaults for RedisOptions.
+func newRedisClient(config *Config) (redis.UniversalClient, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:      config.RedisAddrs,
+		MasterName: config.RedisMasterName,
+	}
+
+	network := "tcp"
+	if config.RedisURL != "" {
+		parsed, err := redis.ParseURL(config.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RedisURL: %w", err)
+		}
+		if len(opts.Addrs) == 0 {
+			opts.Addrs = []string{parsed.Addr}
+			network = parsed.Network
+		}
+		urlOptions(opts, parsed)
+	}
+
+	if err := config.Redis.apply(opts); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case config.RedisCluster:
+		return redis.NewClusterClient(opts.Cluster()), nil
+	case opts.MasterName != "" || len(opts.Addrs) > 1:
+		return redis.NewUniversalClient(opts), nil
+	default:
+		simple := opts.Simple()
+		simple.Network = network
+		return redis.NewClient(simple), nil
+	}
+}
+
+// urlOptions copies the settings a Redis URL can carry
+func urlOptions(opts *redis.UniversalOptions, parsed *redis.Options) {
+	opts.ClientName = parsed.ClientName
+	opts.DB = parsed.DB
+	opts.Protocol = parsed.Protocol
+	opts.Username = parsed.Username
+	opts.Password = parsed.Password
+	opts.MaxRetries = parsed.MaxRetries
+	opts.MinRetryBackoff = parsed.MinRetryBackoff
+	opts.MaxRetryBackoff = parsed.MaxRetryBackoff
+	opts.DialTimeout = parsed.DialTimeout
+	opts.ReadTimeout = parsed.ReadTimeout
+	opts.WriteTimeout = parsed.WriteTimeout
+	opts.PoolFIFO = parsed.PoolFIFO
+	opts.PoolSize = parsed.PoolSize
+	opts.PoolTimeout = parsed.PoolTimeout
+	opts.MinIdleConns = parsed.MinIdleConns
+	opts.MaxIdleConns = parsed.MaxIdleConns
+	opts.MaxActiveConns = parsed.MaxActiveConns
+	opts.ConnMaxIdleTime = parsed.ConnMaxIdleTime
+	opts.ConnMaxLifetime = parsed.ConnMaxLifetime
+	opts.TLSConfig = parsed.TLSConfig
+}
+
+// apply overrides opts with the fields that are set
+func (o RedisOptions) apply(opts *redis.UniversalOptions) error {
+	setString := func(dst *string, src string) {
+		if src != "" {
+			*dst = src
+		}
+	}
+	setDuration := func(dst *time.Duration, src time.Duration) {
+		if src != 0 {
+			*dst = src
+		}
+	}
+
+	setString(&opts.Username, o.Username)
+	setString(&opts.Password, o.Password)
+	setString(&opts.SentinelUsername, o.SentinelUsername)
+	setString(&opts.SentinelPassword, o.SentinelPassword)
+	setString(&opts.ClientName, o.ClientName)
+	setDuration(&opts.PoolTimeout, o.PoolTimeout)
+	setDuration(&opts.DialTimeout, o.DialTimeout)
+	setDuration(&opts.ReadTimeout, o.ReadTimeout)
+	setDuration(&opts.WriteTimeout, o.WriteTimeout)
+	if o.MaxRetries != 0 {
+		opts.MaxRetries = o.MaxRetries
+	}
+	if o.PoolSize > 0 {
+		opts.PoolSize = o.PoolSize
+	}
+	if o.MinIdleConns > 0 {
+		opts.MinIdleConns = o.MinIdleConns
+	}
+
+	if o.TLSConfig != nil {
+		opts.TLSConfig = o.TLSConfig
+	}
+	if o.TLSCertFile == "" && o.TLSKeyFile == "" && o.TLSCAFile == "" {
+		return nil
+	}
+
+	if opts.TLSConfig == nil {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	} else {
+		opts.TLSConfig = opts.TLSConfig.Clone()
+	}
+
+	if o.TLSCertFile != "" || o.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.TLSCertFile, o.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load Redis client certificate: %w", err)
+		}
+		opts.TLSConfig.Certificates = append(opts.TLSConfig.Certificates, cert)
+	}
+
+	if o.TLSCAFile != "" {
+		pem, err := os.ReadFile(o.TLSCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in Redis CA file %s", o.TLSCAFile)
+		}
+		opts.TLSConfig.RootCAs = pool
+	}
+	return nil
 }
 
 // Get retrieves a value from Redis
@@ -49,29 +218,59 @@ func (r *RedisStore) Get(ctx context.Context, key string) (interface{}, error) {
 	return val, nil
 }
 
+// GetWithTTL retrieves a value and its remaining TTL, 0 if it does not
+// expire, in a single round trip
+func (r *RedisStore) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) {
+	pipe := r.client.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	pipe.Exec(ctx)
+
+	val, err := get.Result()
+	if err == redis.Nil {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// PTTL reports -1 for keys without expiry
+	ttl := pttl.Val()
+	if ttl < 0 {
+		ttl = 0
+	}
+	return val, ttl, nil
+}
+
 // Set stores a value in Redis
 func (r *RedisStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	// Serialize value to JSON if it's not a string
-	var data interface{}
+	data, err := redisValue(value)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, key, data, ttl).Err()
+}
+
+// redisValue serializes a value to JSON if it's not a string or byte slice
+func redisValue(value interface{}) (interface{}, error) {
 	switch v := value.(type) {
 	case string:
-		data = v
+		return v, nil
 	case []byte:
-		data = v
+		return v, nil
 	default:
-		jsonData, err := json.Marshal(value)
-		if err != nil {
-			return err
-		}
-		data = jsonData
+		return json.Marshal(value)
 	}
-
-	return r.client.Set(ctx, key, data, ttl).Err()
 }
 
-// Delete removes a value from Redis
+// Delete removes a value from Redis, along with its tag index entries.
+// Keys without tags are unlinked without touching the tag index.
 func (r *RedisStore) Delete(ctx context.Context, key string) error {
-	return r.client.Del(ctx, key).Err()
+	if r.cluster != nil {
+		return r.clusterDelete(ctx, key)
+	}
+	return deleteTaggedScript.Run(ctx, r.client, []string{key, tagsKeyPrefix + key}, tagKeyPrefix).Err()
 }
 
 // Has checks if a key exists in Redis
@@ -90,9 +289,67 @@ func (r *RedisStore) Decrement(ctx context.Context, key string, delta int64) (in
 	return r.client.DecrBy(ctx, key, delta).Result()
 }
 
-// Clear removes all entries from Redis (dangerous!)
+// Clear removes all entries from Redis by flushing the whole database,
+// including data that doesn't belong to this cache. It fails with
+// ErrFlushDisabled unless enabled with WithFlush.
 func (r *RedisStore) Clear(ctx context.Context) error {
-	return r.client.FlushDB(ctx).Err()
+	if !r.allowFlush {
+		return ErrFlushDisabled
+	}
+	return r.forEachNode(ctx, func(ctx context.Context, node redis.UniversalClient) error {
+		return node.FlushDB(ctx).Err()
+	})
+}
+
+// WithFlush allows Clear to flush the whole Redis database
+func (r *RedisStore) WithFlush() *RedisStore {
+	r.allowFlush = true
+	return r
+}
+
+// deletePrefixBatch is how many keys are scanned and unlinked per round trip
+const deletePrefixBatch = 500
+
+// DeletePrefix removes all keys starting with prefix, along with their tag
+// index, using SCAN and UNLINK so Redis is never blocked for long.
+// On a Redis Cluster every master is scanned.
+func (r *RedisStore) DeletePrefix(ctx context.Context, prefix string) error {
+	pattern := escapeGlob(prefix) + "*"
+
+	return r.forEachNode(ctx, func(ctx context.Context, node redis.UniversalClient) error {
+		for _, match := range []string{pattern, tagsKeyPrefix + pattern, tagKeyPrefix + pattern} {
+			iter := node.Scan(ctx, 0, match, deletePrefixBatch).Iterator()
+
+			batch := make([]string, 0, deletePrefixBatch)
+			for iter.Next(ctx) {
+				batch = append(batch, iter.Val())
+				if len(batch) == deletePrefixBatch {
+					if err := r.unlink(ctx, batch); err != nil {
+						return err
+					}
+					batch = batch[:0]
+				}
+			}
+			if err := iter.Err(); err != nil {
+				return err
+			}
+
+			if len(batch) > 0 {
+				if err := r.unlink(ctx, batch); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// globEscaper escapes the characters SCAN MATCH treats as wildcards
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// escapeGlob makes s match itself literally in a SCAN pattern
+func escapeGlob(s string) string {
+	return globEscaper.Replace(s)
 }
 
 // Close closes the Redis connection
@@ -138,9 +395,86 @@ func (r *RedisStore) Ping(ctx context.Context) error {
 	return r.client.Ping(ctx).Err()
 }
 
-// IncrementWithExpiry increments and sets expiry
+// statsTimeout bounds the INFO call behind Evictions and Expirations
+const statsTimeout = time.Second
+
+// statsInterval is how long INFO counters are reused before being fetched
+// again, so Stats calls and metric scrapes cost at most one INFO per interval
+const statsInterval = time.Second
+
+// redisCounters caches the server counters read from INFO
+type redisCounters struct {
+	mu          sync.Mutex
+	fetched     time.Time
+	evictions   uint64
+	expirations uint64
+}
+
+// Evictions returns the number of keys Redis evicted under memory pressure.
+// The counter is server-wide, so it includes keys of other clients.
+func (r *RedisStore) Evictions() uint64 {
+	evictions, _ := r.infoStats()
+	return evictions
+}
+
+// Expirations returns the number of keys Redis expired.
+// The counter is server-wide, so it includes keys of other clients.
+func (r *RedisStore) Expirations() uint64 {
+	_, expirations := r.infoStats()
+	return expirations
+}
+
+// infoStats returns the evicted and expired key counters from the stats
+// section of INFO, summed over the masters of a Redis Cluster. The values
+// are refreshed at most once per statsInterval, and the last values are
+// kept if INFO fails.
+func (r *RedisStore) infoStats() (evictions, expirations uint64) {
+	r.counters.mu.Lock()
+	defer r.counters.mu.Unlock()
+
+	if time.Since(r.counters.fetched) < statsInterval {
+		return r.counters.evictions, r.counters.expirations
+	}
+	r.counters.fetched = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	err := r.forEachNode(ctx, func(ctx context.Context, node redis.UniversalClient) error {
+		info, err := node.Info(ctx, "stats").Result()
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		evictions += infoField(info, "evicted_keys")
+		expirations += infoField(info, "expired_keys")
+		return nil
+	})
+	if err == nil {
+		r.counters.evictions = evictions
+		r.counters.expirations = expirations
+	}
+	return r.counters.evictions, r.counters.expirations
+}
+
+// infoField reads a numeric field from an INFO reply, or 0 if missing
+func infoField(info, field string) uint64 {
+	for _, line := range strings.Split(info, "\r\n") {
+		if value, found := strings.CutPrefix(line, field+":"); found {
+			n, _ := strconv.ParseUint(value, 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// IncrementWithExpiry increments and sets expiry in one MULTI/EXEC transaction.
+// Use RateLimiter for rate limiting.
 func (r *RedisStore) IncrementWithExpiry(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
-	pipe := r.client.Pipeline()
+	pipe := r.client.TxPipeline()
 	incrCmd := pipe.IncrBy(ctx, key, delta)
 	pipe.Expire(ctx, key, ttl)
 
@@ -151,11 +485,365 @@ func (r *RedisStore) IncrementWithExpiry(ctx context.Context, key string, delta
 	return incrCmd.Val(), nil
 }
 
-// GetClient returns the underlying Redis client for advanced operations
+// Redis key prefixes used by the tag index: a set of keys per tag, and a
+// set of tags per key so a key can be removed from its tags
+const (
+	tagKeyPrefix  = "__tag:"
+	tagsKeyPrefix = "__tags:"
+)
+
+// tagPruneSample is the number of members checked for expired keys each
+// time a key is added to a tag set
+const tagPruneSample = 10
+
+// setTaggedScript stores a value and moves its key to a new set of tags.
+// Tag sets live at least as long as their longest-lived key, so index
+// entries for expired keys disappear with them. A tag set holding a key
+// without expiry never expires, so every write also samples a few members
+// and removes those whose keys are gone.
+var setTaggedScript = redis.NewScript(`
+local ttl = tonumber(ARGV[2])
+
+local function extend(key, existed)
+	if ttl == 0 then
+		redis.call("PERSIST", key)
+		return
+	end
+	local current = redis.call("PTTL", key)
+	if not existed or (current >= 0 and current < ttl) then
+		redis.call("PEXPIRE", key, ttl)
+	end
+end
+
+local function prune(key)
+	for _, member in ipairs(redis.call("SRANDMEMBER", key, ARGV[4])) do
+		if redis.call("EXISTS", member) == 0 then
+			redis.call("SREM", key, member)
+		end
+	end
+end
+
+for _, tag in ipairs(redis.call("SMEMBERS", KEYS[2])) do
+	redis.call("SREM", ARGV[3] .. tag, KEYS[1])
+end
+redis.call("DEL", KEYS[2])
+
+if ttl > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+
+for i = 3, #KEYS do
+	local existed = redis.call("EXISTS", KEYS[i]) == 1
+	if existed then
+		prune(KEYS[i])
+	end
+	redis.call("SADD", KEYS[i], KEYS[1])
+	extend(KEYS[i], existed)
+	redis.call("SADD", KEYS[2], ARGV[i + 2])
+end
+if #KEYS > 2 then
+	extend(KEYS[2], false)
+end
+return 1
+`)
+
+// deleteTaggedScript deletes a key and removes it from its tags, or only
+// unlinks it if it has no tag index
+var deleteTaggedScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[2]) == 0 then
+	return redis.call("UNLINK", KEYS[1])
+end
+for _, tag in ipairs(redis.call("SMEMBERS", KEYS[2])) do
+	redis.call("SREM", ARGV[1] .. tag, KEYS[1])
+end
+return redis.call("DEL", KEYS[1], KEYS[2])
+`)
+
+// invalidateTagScript deletes every key carrying a tag and returns them
+var invalidateTagScript = redis.NewScript(`
+local keys = redis.call("SMEMBERS", KEYS[1])
+for _, key in ipairs(keys) do
+	local index = ARGV[2] .. key
+	for _, tag in ipairs(redis.call("SMEMBERS", index)) do
+		redis.call("SREM", ARGV[1] .. tag, key)
+	end
+	redis.call("DEL", key, index)
+end
+redis.call("DEL", KEYS[1])
+return keys
+`)
+
+// SetWithTags stores a value and associates the key with the given tags.
+// Overwriting the key with SetWithTags replaces its tags, while a plain Set
+// leaves it in its previous tags until it is deleted or they are invalidated.
+func (r *RedisStore) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	data, err := redisValue(value)
+	if err != nil {
+		return err
+	}
+
+	ttlMillis := ttl.Milliseconds()
+	if ttl > 0 && ttlMillis == 0 {
+		ttlMillis = 1
+	}
+
+	if r.cluster != nil {
+		return r.clusterSetWithTags(ctx, key, data, ttlMillis, tags)
+	}
+
+	keys := make([]string, 0, len(tags)+2)
+	keys = append(keys, key, tagsKeyPrefix+key)
+	args := make([]interface{}, 0, len(tags)+4)
+	args = append(args, data, ttlMillis, tagKeyPrefix, tagPruneSample)
+	for _, tag := range tags {
+		keys = append(keys, tagKeyPrefix+tag)
+		args = append(args, tag)
+	}
+
+	return setTaggedScript.Run(ctx, r.client, keys, args...).Err()
+}
+
+// InvalidateTags removes all keys associated with any of the tags
+func (r *RedisStore) InvalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	var removed []string
+	for _, tag := range tags {
+		var keys []string
+		var err error
+		if r.cluster != nil {
+			keys, err = r.clusterInvalidateTag(ctx, tag)
+		} else {
+			keys, err = invalidateTagScript.Run(ctx, r.client, []string{tagKeyPrefix + tag}, tagKeyPrefix, tagsKeyPrefix).StringSlice()
+		}
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, keys...)
+	}
+	return removed, nil
+}
+
+// Lock keys. The key is wrapped in a hash tag so the lease and its fencing
+// counter live in the same Redis Cluster slot. Stampede leases have a key
+// space of their own, so they never block application locks on the same key.
+const (
+	lockKeyPrefix     = "__lock:"
+	fenceKeyPrefix    = "__fence:"
+	stampedeKeyPrefix = "__stampede:"
+)
+
+// lockKeys returns the lease and fencing counter keys of a lock
+func lockKeys(key string) []string {
+	tag := "{" + key + "}"
+	return []string{lockKeyPrefix + tag, fenceKeyPrefix + tag}
+}
+
+// acquireLockScript takes a lease with SET NX PX and returns the next
+// fencing token, or 0 if the lock is held
+var acquireLockScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+
+// extendLockScript resets the lease only if it is still held by the given owner
+var extendLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLockScript deletes a lease only if it is still held by the given owner
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// millis converts a duration to milliseconds, rounding up so short durations don't become 0
+func millis(ttl time.Duration) int64 {
+	ms := int64((ttl + time.Millisecond - 1) / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	return ms
+}
+
+// AcquireLock takes the lock on key for ttl if it is free. Fencing counters
+// are kept without expiry so tokens keep increasing across leases, which
+// leaves one counter key per lock key: use a bounded set of lock keys.
+func (r *RedisStore) AcquireLock(ctx context.Context, key, owner string, ttl time.Duration) (int64, bool, error) {
+	token, err := acquireLockScript.Run(ctx, r.client, lockKeys(key), owner, millis(ttl)).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	return token, token > 0, nil
+}
+
+// ExtendLock resets the lease of a lock still held by owner
+func (r *RedisStore) ExtendLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	n, err := extendLockScript.Run(ctx, r.client, lockKeys(key)[:1], owner, millis(ttl)).Int64()
+	return n == 1, err
+}
+
+// ReleaseLock releases a lock still held by owner
+func (r *RedisStore) ReleaseLock(ctx context.Context, key, owner string) (bool, error) {
+	n, err := releaseLockScript.Run(ctx, r.client, lockKeys(key)[:1], owner).Int64()
+	return n == 1, err
+}
+
+// acquireLease takes a stampede lease on key with SET NX PX, without a fencing counter
+func (r *RedisStore) acquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, stampedeKeyPrefix+key, owner, ttl).Result()
+}
+
+// releaseLease releases a stampede lease still held by owner
+func (r *RedisStore) releaseLease(ctx context.Context, key, owner string) error {
+	return releaseLockScript.Run(ctx, r.client, []string{stampedeKeyPrefix + key}, owner).Err()
+}
+
+// Rate limit scripts. Each returns {allowed, remaining, retry after} with
+// times in microseconds read from the server clock, so every instance agrees.
+// ARGV holds the limit, the window and the number of requests.
+var rateLimitScripts = map[RateLimitAlgorithm]*redis.Script{
+	// The window is in milliseconds and starts with the first request
+	FixedWindow: redis.NewScript(`
+local limit, window, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	redis.call("SET", KEYS[1], n, "PX", window)
+	return {1, limit - n, 0}
+end
+local count = tonumber(redis.call("GET", KEYS[1]))
+if count + n > limit then
+	return {0, limit - count, ttl * 1000}
+end
+return {1, limit - redis.call("INCRBY", KEYS[1], n), 0}
+`),
+
+	// ARGV[4] makes the log members of concurrent callers unique
+	SlidingWindowLog: redis.NewScript(`
+local limit, window, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+if count + n > limit then
+	local oldest = redis.call("ZRANGE", KEYS[1], count + n - limit - 1, count + n - limit - 1, "WITHSCORES")
+	return {0, limit - count, tonumber(oldest[2]) + window - now}
+end
+for i = 1, n do
+	redis.call("ZADD", KEYS[1], now, string.format("%.0f:%d:%s", now, i, ARGV[4]))
+end
+redis.call("PEXPIRE", KEYS[1], math.ceil(window / 1000))
+return {1, limit - count - n, 0}
+`),
+
+	SlidingWindowCounter: redis.NewScript(`
+local limit, window, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local index = math.floor(now / window)
+local state = redis.call("HMGET", KEYS[1], "w", "c", "p")
+local w, c, p = tonumber(state[1]), tonumber(state[2]) or 0, tonumber(state[3]) or 0
+if w ~= index then
+	if w == index - 1 then p = c else p = 0 end
+	c = 0
+end
+local elapsed = now - index * window
+local estimate = p * (window - elapsed) / window + c
+if estimate + n > limit then
+	local retry
+	local free = limit - c - n
+	if free >= 0 then
+		retry = math.ceil(window * (1 - free / p)) - elapsed
+	else
+		retry = window - elapsed + math.ceil(window * (1 - (limit - n) / c))
+	end
+	return {0, math.floor(limit - estimate), retry}
+end
+redis.call("HMSET", KEYS[1], "w", index, "c", c + n, "p", p)
+redis.call("PEXPIRE", KEYS[1], math.ceil(2 * window / 1000))
+return {1, math.floor(limit - estimate - n), 0}
+`),
+
+	TokenBucket: redis.NewScript(`
+local limit, window, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local rate = limit / window
+local state = redis.call("HMGET", KEYS[1], "t", "u")
+local tokens = limit
+if state[1] then
+	tokens = math.min(limit, tonumber(state[1]) + (now - tonumber(state[2])) * rate)
+end
+if tokens < n then
+	return {0, math.floor(tokens), math.ceil((n - tokens) / rate)}
+end
+tokens = tokens - n
+redis.call("HMSET", KEYS[1], "t", tokens, "u", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil((limit - tokens) / rate / 1000) + 1)
+return {1, math.floor(tokens), 0}
+`),
+}
+
+// AllowN records n requests against key if limit allows all of them,
+// atomically in a Lua script
+func (r *RedisStore) AllowN(ctx context.Context, key string, limit RateLimit, n int64) (RateLimitResult, error) {
+	script, ok := rateLimitScripts[limit.Algorithm]
+	if !ok {
+		return RateLimitResult{}, ErrInvalidRateLimit
+	}
+
+	window := max(limit.Window.Microseconds(), 1)
+	if limit.Algorithm == FixedWindow {
+		window = millis(limit.Window)
+	}
+	args := []interface{}{limit.Limit, window, n}
+	if limit.Algorithm == SlidingWindowLog {
+		nonce, err := newLockOwner()
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		args = append(args, nonce)
+	}
+
+	reply, err := script.Run(ctx, r.client, []string{rateLimitKey(key, limit)}, args...).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	if reply[0] == 1 {
+		return limit.allowed(reply[1]), nil
+	}
+	return limit.denied(reply[1], reply[2]*int64(time.Microsecond)), nil
+}
+
+// GetClient returns the underlying Redis client for advanced operations.
+// It is set for single node and Sentinel setups, and nil on a Redis Cluster
+// or for a store created with a client that is not a *redis.Client.
+//
+// Deprecated: use UniversalClient, which works with every setup.
 func (r *RedisStore) GetClient() *redis.Client {
+	client, _ := r.client.(*redis.Client)
+	return client
+}
+
+// UniversalClient returns the underlying Redis client, which is a
+// *redis.ClusterClient on a Redis Cluster
+func (r *RedisStore) UniversalClient() redis.UniversalClient {
 	return r.client
 }
 
 var (
+	// ErrRedisUnavailable is returned while the circuit breaker around Redis
+	// is open and no fallback store is configured
 	ErrRedisUnavailable = errors.New("redis unavailable")
+
+	// ErrFlushDisabled is returned by RedisStore.Clear unless flushing was enabled
+	ErrFlushDisabled = errors.New("flushing the Redis database is disabled; clear a namespace or enable AllowFlush")
 )
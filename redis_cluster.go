@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis Cluster rejects scripts and commands touching keys in different hash
+// slots, and a key and its tag sets usually are. On a cluster the tag index
+// is therefore maintained with pipelined commands instead of Lua scripts:
+// each command is atomic, but a concurrent writer may observe a key whose
+// tag index is briefly out of date.
+
+// forEachNode runs fn on every master of a Redis Cluster, or once on the client
+func (r *RedisStore) forEachNode(ctx context.Context, fn func(ctx context.Context, node redis.UniversalClient) error) error {
+	if r.cluster == nil {
+		return fn(ctx, r.client)
+	}
+	return r.cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		return fn(ctx, node)
+	})
+}
+
+// unlink removes keys, one command per key on a Redis Cluster
+func (r *RedisStore) unlink(ctx context.Context, keys []string) error {
+	if r.cluster == nil {
+		return r.client.Unlink(ctx, keys...).Err()
+	}
+
+	pipe := r.cluster.Pipeline()
+	for _, key := range keys {
+		pipe.Unlink(ctx, key)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// clusterDelete deletes a key and removes it from its tags
+func (r *RedisStore) clusterDelete(ctx context.Context, key string) error {
+	tags, err := r.client.SMembers(ctx, tagsKeyPrefix+key).Result()
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return r.client.Unlink(ctx, key).Err()
+	}
+
+	pipe := r.client.Pipeline()
+	for _, tag := range tags {
+		pipe.SRem(ctx, tagKeyPrefix+tag, key)
+	}
+	pipe.Del(ctx, key)
+	pipe.Del(ctx, tagsKeyPrefix+key)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// clusterSetWithTags stores a value and moves its key to a new set of tags,
+// extending and pruning tag sets like setTaggedScript
+func (r *RedisStore) clusterSetWithTags(ctx context.Context, key string, data interface{}, ttlMillis int64, tags []string) error {
+	pipe := r.client.Pipeline()
+	previous := pipe.SMembers(ctx, tagsKeyPrefix+key)
+	ttls := make([]*redis.DurationCmd, len(tags))
+	samples := make([]*redis.StringSliceCmd, len(tags))
+	for i, tag := range tags {
+		ttls[i] = pipe.PTTL(ctx, tagKeyPrefix+tag)
+		samples[i] = pipe.SRandMemberN(ctx, tagKeyPrefix+tag, tagPruneSample)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	stale, err := r.clusterMissing(ctx, samples)
+	if err != nil {
+		return err
+	}
+
+	ttl := time.Duration(ttlMillis) * time.Millisecond
+
+	pipe = r.client.Pipeline()
+	for _, tag := range previous.Val() {
+		pipe.SRem(ctx, tagKeyPrefix+tag, key)
+	}
+	pipe.Del(ctx, tagsKeyPrefix+key)
+	pipe.Set(ctx, key, data, ttl)
+
+	for i, tag := range tags {
+		if len(stale[i]) > 0 {
+			pipe.SRem(ctx, tagKeyPrefix+tag, stale[i]...)
+		}
+		pipe.SAdd(ctx, tagKeyPrefix+tag, key)
+		pipe.SAdd(ctx, tagsKeyPrefix+key, tag)
+
+		// PTTL reports -2 for a missing key and -1 for a key without expiry
+		current := ttls[i].Val()
+		switch {
+		case ttl == 0:
+			pipe.Persist(ctx, tagKeyPrefix+tag)
+		case current == -2 || (current >= 0 && current < ttl):
+			pipe.PExpire(ctx, tagKeyPrefix+tag, ttl)
+		}
+	}
+	if len(tags) > 0 {
+		if ttl == 0 {
+			pipe.Persist(ctx, tagsKeyPrefix+key)
+		} else {
+			pipe.PExpire(ctx, tagsKeyPrefix+key, ttl)
+		}
+	}
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// clusterMissing returns, for each sample of tag set members, the members
+// whose keys no longer exist
+func (r *RedisStore) clusterMissing(ctx context.Context, samples []*redis.StringSliceCmd) ([][]interface{}, error) {
+	pipe := r.client.Pipeline()
+	exists := make([][]*redis.IntCmd, len(samples))
+	for i, sample := range samples {
+		for _, member := range sample.Val() {
+			exists[i] = append(exists[i], pipe.Exists(ctx, member))
+		}
+	}
+	if pipe.Len() > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	missing := make([][]interface{}, len(samples))
+	for i, sample := range samples {
+		for j, member := range sample.Val() {
+			if exists[i][j].Val() == 0 {
+				missing[i] = append(missing[i], member)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// clusterInvalidateTag deletes every key carrying a tag and returns them
+func (r *RedisStore) clusterInvalidateTag(ctx context.Context, tag string) ([]string, error) {
+	keys, err := r.client.SMembers(ctx, tagKeyPrefix+tag).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := r.client.Pipeline()
+	indexes := make([]*redis.StringSliceCmd, len(keys))
+	for i, key := range keys {
+		indexes[i] = pipe.SMembers(ctx, tagsKeyPrefix+key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	pipe = r.client.Pipeline()
+	for i, key := range keys {
+		for _, other := range indexes[i].Val() {
+			if other != tag {
+				pipe.SRem(ctx, tagKeyPrefix+other, key)
+			}
+		}
+		pipe.Del(ctx, key)
+		pipe.Del(ctx, tagsKeyPrefix+key)
+	}
+	pipe.Del(ctx, tagKeyPrefix+tag)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
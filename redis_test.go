@@ -0,0 +1,85 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+// unreachableRedis is an address nothing listens on
+const unreachableRedis = "redis://127.0.0.1:1/0"
+
+func TestRedisLazyConnect(t *testing.T) {
+	config := cache.DefaultConfig().
+		WithBackend(cache.BackendRedis).
+		WithRedisURL(unreachableRedis)
+
+	start := time.Now()
+	if _, err := cache.New(config.WithRedisOptions(cache.RedisOptions{
+		DialTimeout: 50 * time.Millisecond,
+		PingTimeout: 100 * time.Millisecond,
+	})); err == nil {
+		t.Fatal("Expected New to fail when Redis is unreachable")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected PingTimeout to bound New, took %v", elapsed)
+	}
+
+	c, err := cache.New(config.WithRedisOptions(cache.RedisOptions{
+		DialTimeout: 50 * time.Millisecond,
+		MaxRetries:  -1,
+		LazyConnect: true,
+	}))
+	if err != nil {
+		t.Fatalf("Expected lazy connect to succeed, got %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Get(context.Background(), "key"); err == nil || errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected a connection error on first use, got %v", err)
+	}
+}
+
+func TestRedisGetClient(t *testing.T) {
+	configs := map[string]*cache.Config{
+		"single": cache.DefaultConfig().
+			WithRedisURL(unreachableRedis),
+		"sentinel": cache.DefaultConfig().
+			WithRedisURL(unreachableRedis).
+			WithRedisSentinel("mymaster", "127.0.0.1:1"),
+	}
+	for name, config := range configs {
+		c, err := cache.New(config.
+			WithBackend(cache.BackendRedis).
+			WithRedisOptions(cache.RedisOptions{LazyConnect: true}))
+		if err != nil {
+			t.Fatalf("Failed to create %s cache: %v", name, err)
+		}
+		defer c.Close()
+
+		store := c.GetStore().(*cache.RedisStore)
+		if store.GetClient() == nil || store.UniversalClient() == nil {
+			t.Errorf("Expected a client for the %s setup", name)
+		}
+	}
+}
+
+func TestRedisTLSFiles(t *testing.T) {
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(ca, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := cache.New(cache.DefaultConfig().
+		WithBackend(cache.BackendRedis).
+		WithRedisURL(unreachableRedis).
+		WithRedisOptions(cache.RedisOptions{TLSCAFile: ca, LazyConnect: true}))
+	if err == nil {
+		t.Error("Expected an invalid CA file to be rejected")
+	}
+}
@@ -0,0 +1,394 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRefreshWorkers is the number of concurrent refreshes when Config.RefreshWorkers is not set
+const defaultRefreshWorkers = 4
+
+// ErrCacheClosed is returned when registering a loader on a closed cache
+var ErrCacheClosed = errors.New("cache is closed")
+
+// LoaderFunc loads the current value of a key for refresh-ahead
+type LoaderFunc func(ctx context.Context, key string) (interface{}, error)
+
+// RefreshOptions configures a loader registered with RegisterLoader
+type RefreshOptions struct {
+	// Ahead is how long before expiry an entry is refreshed
+	// Default: a tenth of the entry TTL, also used if Ahead is not shorter than the TTL
+	Ahead time.Duration
+
+	// AccessWindow only refreshes entries read within this long before the
+	// refresh is due; entries that weren't are left to expire
+	// Default: 0 (entries must have been read since they were last written)
+	AccessWindow time.Duration
+}
+
+// refreshLoader is a loader registered on a cache or namespace
+type refreshLoader struct {
+	cache   *Cache
+	pattern string
+	loader  LoaderFunc
+	opts    RefreshOptions
+}
+
+// refreshWrite describes how a key was written, so a refresh can write it the same way
+type refreshWrite struct {
+	ttl     time.Duration
+	softTTL time.Duration // fresh period of a GetOrSetStale envelope, 0 for plain values
+	tags    []string      // tags including the namespace prefix
+}
+
+// refreshEntry is a written key waiting for its refresh
+type refreshEntry struct {
+	loader     *refreshLoader
+	key        string   // key relative to the loader's cache
+	tags       []string // tags relative to the loader's cache
+	ttl        time.Duration
+	softTTL    time.Duration
+	due        time.Time
+	lastAccess int64 // Unix nanoseconds, 0 if never read
+}
+
+// fullKey returns the key including the namespace prefix
+func (e *refreshEntry) fullKey() string {
+	return e.loader.cache.prefix + e.key
+}
+
+// recentlyRead reports whether the entry was read recently enough to be refreshed at now
+func (e *refreshEntry) recentlyRead(now time.Time) bool {
+	lastAccess := atomic.LoadInt64(&e.lastAccess)
+	if lastAccess == 0 {
+		return false
+	}
+
+	window := e.loader.opts.AccessWindow
+	return window <= 0 || now.Sub(time.Unix(0, lastAccess)) <= window
+}
+
+// refresher refreshes entries shortly before they expire, on behalf of
+// a cache and all of its namespaces
+type refresher struct {
+	mu      sync.Mutex
+	loaders []*refreshLoader
+	entries map[string]*refreshEntry
+	running map[string]*refreshEntry // entries handed to a worker
+	active  atomic.Bool
+
+	workers int
+	wake    chan struct{}
+	jobs    chan *refreshEntry
+	ctx     context.Context
+	cancel  context.CancelFunc
+	start   sync.Once
+	wg      sync.WaitGroup
+}
+
+// newRefresher creates an idle refresher; workers start with the first loader
+func newRefresher(workers int) *refresher {
+	if workers <= 0 {
+		workers = defaultRefreshWorkers
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &refresher{
+		entries: make(map[string]*refreshEntry),
+		running: make(map[string]*refreshEntry),
+		workers: workers,
+		wake:    make(chan struct{}, 1),
+		jobs:    make(chan *refreshEntry),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// RegisterLoader keeps keys matching pattern warm. Once a matching key has
+// been written through the cache with a TTL, it is reloaded with loader and
+// rewritten with the same TTL shortly before it expires, as long as it was
+// read recently. Keys written with SetWithTags are rewritten with the same
+// tags, and keys written by GetOrSetStale with the same soft TTL. Patterns use path.Match syntax, e.g. "dashboard:*", and are
+// matched against keys relative to the namespace of c. Refreshes run on a
+// bounded pool of Config.RefreshWorkers goroutines and are cancelled by Close.
+func (c *Cache) RegisterLoader(pattern string, loader LoaderFunc, opts RefreshOptions) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	return c.refresh.register(&refreshLoader{
+		cache:   c,
+		pattern: pattern,
+		loader:  loader,
+		opts:    opts,
+	})
+}
+
+// register adds a loader and starts the scheduler and workers
+func (r *refresher) register(loader *refreshLoader) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ctx.Err() != nil {
+		return ErrCacheClosed
+	}
+
+	r.loaders = append(r.loaders, loader)
+	r.active.Store(true)
+
+	r.start.Do(func() {
+		r.wg.Add(r.workers + 1)
+		go r.schedule()
+		for i := 0; i < r.workers; i++ {
+			go r.work()
+		}
+	})
+	return nil
+}
+
+// match returns the loader for a full key and the key relative to the loader's cache.
+// The caller must hold r.mu.
+func (r *refresher) match(fullKey string) (*refreshLoader, string) {
+	for _, loader := range r.loaders {
+		if !strings.HasPrefix(fullKey, loader.cache.prefix) {
+			continue
+		}
+		key := fullKey[len(loader.cache.prefix):]
+		if matched, _ := path.Match(loader.pattern, key); matched {
+			return loader, key
+		}
+	}
+	return nil, ""
+}
+
+// written schedules a refresh for a key that was just stored, replacing
+// any refresh of an older value still running
+func (r *refresher) written(fullKey string, write refreshWrite) {
+	if !r.active.Load() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.running, fullKey)
+
+	ttl := write.ttl
+	loader, key := r.match(fullKey)
+	if loader == nil || ttl <= 0 {
+		delete(r.entries, fullKey)
+		return
+	}
+
+	// Tags outside the loader's namespace can't be written back through it
+	tags := make([]string, 0, len(write.tags))
+	for _, tag := range write.tags {
+		if !strings.HasPrefix(tag, loader.cache.prefix) {
+			delete(r.entries, fullKey)
+			return
+		}
+		tags = append(tags, tag[len(loader.cache.prefix):])
+	}
+
+	ahead := loader.opts.Ahead
+	if ahead <= 0 || ahead >= ttl {
+		ahead = ttl / 10
+	}
+	entry := &refreshEntry{
+		loader:  loader,
+		key:     key,
+		tags:    tags,
+		ttl:     ttl,
+		softTTL: write.softTTL,
+		due:     time.Now().Add(ttl - ahead),
+	}
+
+	// With an access window, reads before the write still count
+	if previous, found := r.entries[fullKey]; found && loader.opts.AccessWindow > 0 {
+		entry.lastAccess = atomic.LoadInt64(&previous.lastAccess)
+	}
+	r.entries[fullKey] = entry
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// accessed records a read of a key
+func (r *refresher) accessed(fullKey string) {
+	if !r.active.Load() {
+		return
+	}
+
+	r.mu.Lock()
+	entry, found := r.entries[fullKey]
+	r.mu.Unlock()
+
+	if found {
+		atomic.StoreInt64(&entry.lastAccess, time.Now().UnixNano())
+	}
+}
+
+// forget stops refreshing a deleted key
+func (r *refresher) forget(fullKey string) {
+	if !r.active.Load() {
+		return
+	}
+
+	r.mu.Lock()
+	delete(r.entries, fullKey)
+	delete(r.running, fullKey)
+	r.mu.Unlock()
+}
+
+// forgetPrefix stops refreshing every key starting with prefix
+func (r *refresher) forgetPrefix(prefix string) {
+	if !r.active.Load() {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for fullKey := range r.entries {
+		if strings.HasPrefix(fullKey, prefix) {
+			delete(r.entries, fullKey)
+		}
+	}
+	for fullKey := range r.running {
+		if strings.HasPrefix(fullKey, prefix) {
+			delete(r.running, fullKey)
+		}
+	}
+}
+
+// current reports whether a running entry was neither removed nor replaced
+// by a newer write while it was loading, and marks it as finished
+func (r *refresher) current(entry *refreshEntry) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fullKey := entry.fullKey()
+	if r.running[fullKey] != entry {
+		return false
+	}
+	delete(r.running, fullKey)
+	return true
+}
+
+// schedule hands due entries to the workers, sleeping until the next one is due
+func (r *refresher) schedule() {
+	defer r.wg.Done()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		due, next := r.due(time.Now())
+		for _, entry := range due {
+			select {
+			case r.jobs <- entry:
+			case <-r.ctx.Done():
+				return
+			}
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(next)
+
+		select {
+		case <-timer.C:
+		case <-r.wake:
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// due removes and returns the entries due at now that were read recently,
+// dropping the ones that weren't, and returns how long until the next is due
+func (r *refresher) due(now time.Time) ([]*refreshEntry, time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*refreshEntry
+	next := time.Hour
+	for fullKey, entry := range r.entries {
+		if wait := entry.due.Sub(now); wait > 0 {
+			if wait < next {
+				next = wait
+			}
+			continue
+		}
+
+		delete(r.entries, fullKey)
+		if entry.recentlyRead(now) {
+			r.running[fullKey] = entry
+			due = append(due, entry)
+		}
+	}
+	return due, next
+}
+
+// work refreshes entries until the refresher is closed
+func (r *refresher) work() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case entry := <-r.jobs:
+			r.reload(entry)
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// reload loads an entry and writes it back the way it was written, which
+// schedules the next refresh. Entries removed or rewritten while loading
+// are not written back.
+func (r *refresher) reload(entry *refreshEntry) {
+	c := entry.loader.cache
+
+	atomic.AddUint64(&c.stats.loaderCalls, 1)
+	start := time.Now()
+	value, err := entry.loader.loader(r.ctx, entry.key)
+	c.stats.observe(OpLoad, start)
+	if !r.current(entry) {
+		return
+	}
+	if err != nil {
+		atomic.AddUint64(&c.stats.loaderErrors, 1)
+		c.events.failed(r.ctx, OpLoad, c.prefix+entry.key, err)
+		return
+	}
+
+	switch {
+	case entry.softTTL > 0:
+		c.setStale(r.ctx, entry.key, value, entry.softTTL, entry.ttl)
+	case len(entry.tags) > 0:
+		c.SetWithTags(r.ctx, entry.key, value, entry.ttl, entry.tags...)
+	default:
+		c.SetWithTTL(r.ctx, entry.key, value, entry.ttl)
+	}
+}
+
+// close cancels running refreshes and waits for the workers to exit
+func (r *refresher) close() {
+	r.mu.Lock()
+	r.cancel()
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
@@ -0,0 +1,209 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+func TestRegisterLoader(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	var calls int32
+	err = c.Namespace("app").RegisterLoader("dashboard:*", func(ctx context.Context, key string) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "fresh " + key, nil
+	}, cache.RefreshOptions{Ahead: 40 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("RegisterLoader failed: %v", err)
+	}
+
+	app := c.Namespace("app")
+	app.SetWithTTL(ctx, "dashboard:sales", "stale", 80*time.Millisecond)
+	app.SetWithTTL(ctx, "dashboard:unread", "stale", 80*time.Millisecond)
+	app.SetWithTTL(ctx, "report", "stale", 80*time.Millisecond)
+
+	app.Get(ctx, "dashboard:sales")
+	app.Get(ctx, "report")
+
+	// Past the original expiry, read entries matching the pattern have been refreshed
+	time.Sleep(100 * time.Millisecond)
+
+	if value, err := app.Get(ctx, "dashboard:sales"); err != nil || value != "fresh dashboard:sales" {
+		t.Errorf("Expected refreshed value, got %v, %v", value, err)
+	}
+
+	// Unread entries and other keys expire
+	if _, err := app.Get(ctx, "dashboard:unread"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected unread entry to expire, got %v", err)
+	}
+	if _, err := app.Get(ctx, "report"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected unmatched entry to expire, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected 1 loader call, got %d", n)
+	}
+
+	if err := c.RegisterLoader("[", nil, cache.RefreshOptions{}); err == nil {
+		t.Error("Expected bad pattern to be rejected")
+	}
+}
+
+func TestRegisterLoaderInvalidation(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	c.RegisterLoader("*", func(ctx context.Context, key string) (interface{}, error) {
+		return "fresh " + key, nil
+	}, cache.RefreshOptions{Ahead: 40 * time.Millisecond})
+
+	app := c.Namespace("app")
+	app.SetWithTags(ctx, "tagged", "stale", 80*time.Millisecond, "group")
+	app.Get(ctx, "tagged")
+	tmp := c.Namespace("tmp")
+	tmp.SetWithTTL(ctx, "cleared", "stale", 80*time.Millisecond)
+	tmp.Get(ctx, "cleared")
+	tmp.Clear(ctx)
+
+	time.Sleep(100 * time.Millisecond)
+
+	// The refreshed key keeps its tags
+	if value, err := app.Get(ctx, "tagged"); err != nil || value != "fresh app:tagged" {
+		t.Fatalf("Expected refreshed value, got %v, %v", value, err)
+	}
+	if err := app.InvalidateTags(ctx, "group"); err != nil {
+		t.Fatalf("InvalidateTags failed: %v", err)
+	}
+
+	// Invalidated and cleared keys are not brought back by refreshes
+	time.Sleep(100 * time.Millisecond)
+	for _, key := range []string{"app:tagged", "tmp:cleared"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, cache.ErrNotFound) {
+			t.Errorf("Expected %s to stay removed, got %v", key, err)
+		}
+	}
+}
+
+func TestRegisterLoaderStale(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	c.RegisterLoader("*", func(ctx context.Context, key string) (interface{}, error) {
+		return "reloaded", nil
+	}, cache.RefreshOptions{Ahead: 60 * time.Millisecond})
+
+	c.GetOrSetStale(ctx, "dashboard", func() (interface{}, error) {
+		return "fetched", nil
+	}, 20*time.Millisecond, 80*time.Millisecond)
+	c.Get(ctx, "dashboard")
+
+	time.Sleep(50 * time.Millisecond)
+
+	// The refresh keeps the stale envelope, so the soft TTL still applies
+	raw, err := c.GetStore().Get(ctx, "dashboard")
+	if data, ok := raw.([]byte); err != nil || !ok || len(data) == 0 || data[0] != 0x8c {
+		t.Errorf("Expected a stale envelope after the refresh, got %v (%v)", raw, err)
+	}
+	if value, err := c.Get(ctx, "dashboard"); err != nil || value != "reloaded" {
+		t.Errorf("Expected reloaded value, got %v, %v", value, err)
+	}
+}
+
+func TestRegisterLoaderDeleteWhileLoading(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c.RegisterLoader("*", func(ctx context.Context, key string) (interface{}, error) {
+		close(started)
+		<-release
+		return "reloaded", nil
+	}, cache.RefreshOptions{Ahead: 40 * time.Millisecond})
+
+	c.SetWithTTL(ctx, "key", "value", 50*time.Millisecond)
+	c.Get(ctx, "key")
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("Loader was not called")
+	}
+
+	// A key deleted while its refresh runs is not written back
+	c.Delete(ctx, "key")
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected deleted key to stay removed, got %v", err)
+	}
+}
+
+func TestRegisterLoaderClose(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	started := make(chan struct{})
+	cancelled := make(chan error, 1)
+	c.RegisterLoader("*", func(ctx context.Context, key string) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		cancelled <- ctx.Err()
+		return nil, ctx.Err()
+	}, cache.RefreshOptions{})
+
+	c.SetWithTTL(ctx, "key", "value", 20*time.Millisecond)
+	c.Get(ctx, "key")
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("Loader was not called")
+	}
+
+	c.Close()
+
+	select {
+	case err := <-cancelled:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	default:
+		t.Error("Expected Close to wait for the cancelled loader")
+	}
+
+	if err := c.RegisterLoader("*", nil, cache.RefreshOptions{}); !errors.Is(err, cache.ErrCacheClosed) {
+		t.Errorf("Expected ErrCacheClosed, got %v", err)
+	}
+}
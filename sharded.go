@@ -0,0 +1,190 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// ShardedMemoryStore spreads keys over independently locked MemoryStore shards
+// so that writes to different keys rarely contend on the same mutex.
+// Each shard runs its own cleanup, so expiring entries never locks the whole store.
+type ShardedMemoryStore struct {
+	shards []*MemoryStore
+}
+
+// NewShardedMemoryStore creates an in-memory cache split into n shards.
+// MaxEntries and MaxBytes are divided evenly between the shards.
+func NewShardedMemoryStore(n int, opts MemoryOptions) *ShardedMemoryStore {
+	if n < 1 {
+		n = 1
+	}
+
+	shardOpts := opts
+	shardOpts.MaxEntries = divideCeil(opts.MaxEntries, n)
+	shardOpts.MaxBytes = divideCeil(opts.MaxBytes, n)
+
+	store := &ShardedMemoryStore{
+		shards: make([]*MemoryStore, n),
+	}
+	for i := range store.shards {
+		store.shards[i] = NewMemoryStoreWithOptions(shardOpts)
+	}
+
+	return store
+}
+
+// divideCeil divides a limit between n shards, keeping 0 as unbounded
+func divideCeil[T int | int64](limit T, n int) T {
+	if limit <= 0 {
+		return 0
+	}
+	return (limit + T(n) - 1) / T(n)
+}
+
+// shard returns the shard responsible for a key
+func (s *ShardedMemoryStore) shard(key string) *MemoryStore {
+	// FNV-1a, inlined to avoid allocating a hash.Hash per call
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return s.shards[hash%uint32(len(s.shards))]
+}
+
+// Get retrieves a value from the cache
+func (s *ShardedMemoryStore) Get(ctx context.Context, key string) (interface{}, error) {
+	return s.shard(key).Get(ctx, key)
+}
+
+// GetWithTTL retrieves a value and its remaining TTL, 0 if it does not expire
+func (s *ShardedMemoryStore) GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error) {
+	return s.shard(key).GetWithTTL(ctx, key)
+}
+
+// Set stores a value in the cache
+func (s *ShardedMemoryStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return s.shard(key).Set(ctx, key, value, ttl)
+}
+
+// SetWithTags stores a value and associates the key with the given tags
+func (s *ShardedMemoryStore) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	return s.shard(key).SetWithTags(ctx, key, value, ttl, tags)
+}
+
+// InvalidateTags removes all keys associated with any of the tags, one shard at a time
+func (s *ShardedMemoryStore) InvalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	var removed []string
+	for _, shard := range s.shards {
+		keys, err := shard.InvalidateTags(ctx, tags...)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, keys...)
+	}
+	return removed, nil
+}
+
+// Delete removes a value from the cache
+func (s *ShardedMemoryStore) Delete(ctx context.Context, key string) error {
+	return s.shard(key).Delete(ctx, key)
+}
+
+// DeletePrefix removes all keys starting with prefix, one shard at a time
+func (s *ShardedMemoryStore) DeletePrefix(ctx context.Context, prefix string) error {
+	for _, shard := range s.shards {
+		if err := shard.DeletePrefix(ctx, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Has checks if a key exists
+func (s *ShardedMemoryStore) Has(ctx context.Context, key string) bool {
+	return s.shard(key).Has(ctx, key)
+}
+
+// Increment increments a numeric value
+func (s *ShardedMemoryStore) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	return s.shard(key).Increment(ctx, key, delta)
+}
+
+// Decrement decrements a numeric value
+func (s *ShardedMemoryStore) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	return s.shard(key).Decrement(ctx, key, delta)
+}
+
+// Clear removes all entries, one shard at a time
+func (s *ShardedMemoryStore) Clear(ctx context.Context) error {
+	for _, shard := range s.shards {
+		if err := shard.Clear(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the cleanup goroutine of every shard
+func (s *ShardedMemoryStore) Close() error {
+	for _, shard := range s.shards {
+		shard.Close()
+	}
+	return nil
+}
+
+// Len returns the number of entries across all shards
+func (s *ShardedMemoryStore) Len() int {
+	total := 0
+	for _, shard := range s.shards {
+		total += shard.Len()
+	}
+	return total
+}
+
+// Bytes returns the approximate total size of all entries
+func (s *ShardedMemoryStore) Bytes() int64 {
+	var total int64
+	for _, shard := range s.shards {
+		total += shard.Bytes()
+	}
+	return total
+}
+
+// Evictions returns how many entries were evicted across all shards
+func (s *ShardedMemoryStore) Evictions() uint64 {
+	var total uint64
+	for _, shard := range s.shards {
+		total += shard.Evictions()
+	}
+	return total
+}
+
+// Expirations returns how many expired entries were reclaimed across all shards
+func (s *ShardedMemoryStore) Expirations() uint64 {
+	var total uint64
+	for _, shard := range s.shards {
+		total += shard.Expirations()
+	}
+	return total
+}
+
+// AcquireLock takes the lock on key in the shard responsible for it
+func (s *ShardedMemoryStore) AcquireLock(ctx context.Context, key, owner string, ttl time.Duration) (int64, bool, error) {
+	return s.shard(key).AcquireLock(ctx, key, owner, ttl)
+}
+
+// ExtendLock resets the lease of a lock still held by owner
+func (s *ShardedMemoryStore) ExtendLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	return s.shard(key).ExtendLock(ctx, key, owner, ttl)
+}
+
+// ReleaseLock releases a lock still held by owner
+func (s *ShardedMemoryStore) ReleaseLock(ctx context.Context, key, owner string) (bool, error) {
+	return s.shard(key).ReleaseLock(ctx, key, owner)
+}
+
+// AllowN applies a rate limit in the shard responsible for key
+func (s *ShardedMemoryStore) AllowN(ctx context.Context, key string, limit RateLimit, n int64) (RateLimitResult, error) {
+	return s.shard(key).AllowN(ctx, key, limit, n)
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// staleHeader marks a value stored by GetOrSetStale. The envelope is
+// header | fresh-until (8 bytes, Unix nanoseconds) | payload kind | payload.
+const staleHeader byte = 0x8c
+
+// staleEnvelopeSize is the size of the envelope before the payload
+const staleEnvelopeSize = 10
+
+// wrapStale packs a value, as encoded by the codec, into a stale envelope.
+// Values that are neither strings nor bytes are JSON encoded with a header,
+// so they read back in their generic form.
+func wrapStale(value interface{}, freshUntil time.Time) ([]byte, error) {
+	data, ok := asBytes(value)
+	kind := payloadKindString
+	if _, isBytes := value.([]byte); isBytes {
+		kind = payloadKindBytes
+	}
+	if !ok {
+		encoded, err := JSONCodec{}.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		data = append([]byte{codecHeaderJSON}, encoded...)
+		kind = payloadKindBytes
+	}
+
+	envelope := make([]byte, staleEnvelopeSize+len(data))
+	envelope[0] = staleHeader
+	binary.BigEndian.PutUint64(envelope[1:9], uint64(freshUntil.UnixNano()))
+	envelope[9] = kind
+	copy(envelope[staleEnvelopeSize:], data)
+	return envelope, nil
+}
+
+// unwrapStale returns the value inside a stale envelope and when it goes stale.
+// It reports false if value is not an envelope.
+func unwrapStale(value interface{}) (interface{}, time.Time, bool) {
+	data, ok := asBytes(value)
+	if !ok || len(data) < staleEnvelopeSize || data[0] != staleHeader {
+		return value, time.Time{}, false
+	}
+
+	freshUntil := time.Unix(0, int64(binary.BigEndian.Uint64(data[1:9])))
+	return payloadValue(data[staleEnvelopeSize:], data[9]), freshUntil, true
+}
+
+// GetOrSetStale is GetOrSet with stale-while-revalidate. Fetched values are
+// fresh for softTTL and kept for hardTTL. A stale value is returned right
+// away while a single background call to fetcher refreshes it; callers only
+// wait for the fetcher on a miss or once hardTTL has passed. A hardTTL of 0
+// keeps stale values until they are evicted or deleted.
+//
+// Without a codec, values that are neither strings nor bytes are stored as
+// JSON and returned in their generic form, as with the Redis backend.
+func (c *Cache) GetOrSetStale(ctx context.Context, key string, fetcher func() (interface{}, error), softTTL, hardTTL time.Duration) (interface{}, error) {
+	if hardTTL > 0 && hardTTL < softTTL {
+		hardTTL = softTTL
+	}
+
+	set := func(ctx context.Context, value interface{}) error {
+		return c.setStale(ctx, key, value, softTTL, hardTTL)
+	}
+
+	value, freshUntil, err := c.getEntry(ctx, key)
+	if err == nil {
+		if !freshUntil.IsZero() && time.Now().After(freshUntil) {
+			c.revalidate(ctx, key, fetcher, set)
+		}
+		return c.decode(value)
+	}
+	if errors.Is(err, ErrNegativeCached) {
+		return nil, err
+	}
+
+	value, err = c.flight.do(ctx, key, func() (interface{}, error) {
+		return c.load(context.WithoutCancel(ctx), key, fetcher, set)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if stored, ok := value.(storedValue); ok {
+		return c.decode(stored.value)
+	}
+	return value, nil
+}
+
+// revalidate refreshes a stale key in the background unless a refresh of
+// the key is already running
+func (c *Cache) revalidate(ctx context.Context, key string, fetcher func() (interface{}, error), set func(ctx context.Context, value interface{}) error) {
+	id := c.prefix + key
+	if _, running := c.revalidating.LoadOrStore(id, struct{}{}); running {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer c.revalidating.Delete(id)
+
+		// Sharing the flight lets callers that miss meanwhile wait for this refresh
+		c.flight.do(ctx, key, func() (interface{}, error) {
+			return c.load(ctx, key, fetcher, set)
+		})
+	}()
+}
+
+// setStale stores a value in a stale envelope that is fresh for softTTL
+func (c *Cache) setStale(ctx context.Context, key string, value interface{}, softTTL, hardTTL time.Duration) error {
+	encoded, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+
+	envelope, err := wrapStale(encoded, time.Now().Add(softTTL))
+	if err != nil {
+		return err
+	}
+
+	return c.setEncoded(ctx, key, value, envelope, refreshWrite{ttl: hardTTL, softTTL: softTTL})
+}
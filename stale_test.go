@@ -0,0 +1,142 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+// testStaleWhileRevalidate checks that stale values are served while a
+// single background refresh runs
+func testStaleWhileRevalidate(t *testing.T, c *cache.Cache) {
+	ctx := context.Background()
+
+	var calls int32
+	release := make(chan struct{})
+	fetcher := func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n > 1 {
+			<-release
+		}
+		return fmt.Sprintf("v%d", n), nil
+	}
+
+	value, err := c.GetOrSetStale(ctx, "swr", fetcher, 50*time.Millisecond, time.Minute)
+	if err != nil || value != "v1" {
+		t.Fatalf("GetOrSetStale failed: %v, %v", value, err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// Stale reads return at once while the fetcher is blocked
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := c.GetOrSetStale(ctx, "swr", fetcher, 50*time.Millisecond, time.Minute); err != nil || value != "v1" {
+				t.Errorf("Expected stale v1, got %v, %v", value, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Expected 2 fetcher calls, got %d", n)
+	}
+
+	// Plain reads see through the envelope
+	if value, err := c.Get(ctx, "swr"); err != nil || value != "v2" {
+		t.Errorf("Expected refreshed v2, got %v, %v", value, err)
+	}
+}
+
+func TestGetOrSetStale(t *testing.T) {
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	testStaleWhileRevalidate(t, c)
+}
+
+func TestStaleHeaderInRawValue(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	// Raw values shaped like a stale envelope are returned unchanged
+	data := []byte{0x8c, 0, 0, 0, 0, 0, 0, 0, 1, 0, 'p', 'a', 'y', 'l', 'o', 'a', 'd'}
+	c.Set(ctx, "raw", data)
+	value, err := c.Get(ctx, "raw")
+	if got, ok := value.([]byte); err != nil || !ok || !bytes.Equal(got, data) {
+		t.Errorf("Expected raw value to round-trip, got %v (%v)", value, err)
+	}
+}
+
+func TestGetOrSetStaleHardExpiry(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(cache.DefaultConfig().WithCodec(cache.JSONCodec{}))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	var calls int32
+	fetcher := func() (interface{}, error) {
+		return map[string]interface{}{"n": float64(atomic.AddInt32(&calls, 1))}, nil
+	}
+
+	c.GetOrSetStale(ctx, "hard", fetcher, 10*time.Millisecond, 20*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	// Past the hard TTL the caller waits for a fresh value
+	value, err := c.GetOrSetStale(ctx, "hard", fetcher, 10*time.Millisecond, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("GetOrSetStale failed: %v", err)
+	}
+	if m, ok := value.(map[string]interface{}); !ok || m["n"] != float64(2) {
+		t.Errorf("Expected second fetch, got %v", value)
+	}
+
+	var dest map[string]int
+	if err := c.GetInto(ctx, "hard", &dest); err != nil || dest["n"] != 2 {
+		t.Errorf("GetInto failed: %v, %v", dest, err)
+	}
+}
+
+func TestGetOrSetStaleRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL not set")
+	}
+
+	c, err := cache.New(&cache.Config{
+		Backend:    cache.BackendRedis,
+		RedisURL:   redisURL,
+		DefaultTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+	defer c.Delete(context.Background(), "swr")
+
+	testStaleWhileRevalidate(t, c)
+}
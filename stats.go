@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Operation names a cache operation whose latency is tracked
+type Operation string
+
+const (
+	// OpGet covers reads, including the lookup done by GetOrSet
+	OpGet Operation = "get"
+
+	// OpSet covers writes
+	OpSet Operation = "set"
+
+	// OpDelete covers deletes
+	OpDelete Operation = "delete"
+
+	// OpLoad covers fetcher calls made by GetOrSet on a miss
+	OpLoad Operation = "load"
+
+	// OpIncrement covers increments
+	OpIncrement Operation = "increment"
+
+	// OpDecrement covers decrements
+	OpDecrement Operation = "decrement"
+)
+
+// operations lists the tracked operations in a fixed order
+var operations = [...]Operation{OpGet, OpSet, OpDelete, OpLoad, OpIncrement, OpDecrement}
+
+// latencyBuckets are the upper bounds of the latency histogram buckets
+var latencyBuckets = [...]time.Duration{
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	1 * time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+}
+
+// LatencyHistogram is a snapshot of the latencies of one operation
+type LatencyHistogram struct {
+	// Buckets are the upper bounds of the histogram buckets
+	Buckets []time.Duration
+
+	// Counts holds the number of operations per bucket: Counts[i] counts operations
+	// slower than Buckets[i-1] that took at most Buckets[i], and the extra last
+	// element counts operations slower than every bucket
+	Counts []uint64
+
+	// Count is the total number of operations
+	Count uint64
+
+	// Sum is the total time spent in the operation
+	Sum time.Duration
+}
+
+// Mean returns the average latency, or 0 if nothing was recorded
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Stats is a snapshot of cache statistics
+type Stats struct {
+	// Hits is the number of reads that found a value
+	Hits uint64
+
+	// Misses is the number of reads that found nothing or failed
+	Misses uint64
+
+	// Sets is the number of successful writes
+	Sets uint64
+
+	// Deletes is the number of successful deletes
+	Deletes uint64
+
+	// Evictions is the number of entries evicted by the store to stay within its limits.
+	// Redis reports a server-wide counter
+	Evictions uint64
+
+	// Expirations is the number of expired entries removed by the store.
+	// Redis reports a server-wide counter
+	Expirations uint64
+
+	// LoaderCalls is the number of fetcher calls made by GetOrSet
+	LoaderCalls uint64
+
+	// LoaderErrors is the number of fetcher calls that returned an error
+	LoaderErrors uint64
+
+	// Latencies holds a latency histogram per operation
+	Latencies map[Operation]LatencyHistogram
+}
+
+// HitRatio returns hits divided by reads, or 0 if there were no reads
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// storeCounters is implemented by stores that count evictions and expirations
+type storeCounters interface {
+	Evictions() uint64
+	Expirations() uint64
+}
+
+// histogram records latencies with atomic counters
+type histogram struct {
+	counts [len(latencyBuckets) + 1]uint64
+	count  uint64
+	sum    int64
+}
+
+// observe records one latency
+func (h *histogram) observe(d time.Duration) {
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if d <= bound {
+			bucket = i
+			break
+		}
+	}
+
+	atomic.AddUint64(&h.counts[bucket], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+// snapshot returns the current state of the histogram
+func (h *histogram) snapshot() LatencyHistogram {
+	snapshot := LatencyHistogram{
+		Buckets: append([]time.Duration(nil), latencyBuckets[:]...),
+		Counts:  make([]uint64, len(h.counts)),
+		Count:   atomic.LoadUint64(&h.count),
+		Sum:     time.Duration(atomic.LoadInt64(&h.sum)),
+	}
+	for i := range h.counts {
+		snapshot.Counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	return snapshot
+}
+
+// reset zeroes the histogram
+func (h *histogram) reset() {
+	for i := range h.counts {
+		atomic.StoreUint64(&h.counts[i], 0)
+	}
+	atomic.StoreUint64(&h.count, 0)
+	atomic.StoreInt64(&h.sum, 0)
+}
+
+// statsRecorder collects cache statistics without locking.
+// Store evictions and expirations are read from the store and reported
+// relative to the values seen at the last reset.
+type statsRecorder struct {
+	hits         uint64
+	misses       uint64
+	sets         uint64
+	deletes      uint64
+	loaderCalls  uint64
+	loaderErrors uint64
+
+	evictionsBase   uint64
+	expirationsBase uint64
+
+	latencies [len(operations)]histogram
+}
+
+// observe records the latency of an operation started at start
+func (r *statsRecorder) observe(op Operation, start time.Time) {
+	for i, candidate := range operations {
+		if candidate == op {
+			r.latencies[i].observe(time.Since(start))
+			return
+		}
+	}
+}
+
+// snapshot returns the current statistics, reading store counters from counters if not nil
+func (r *statsRecorder) snapshot(counters storeCounters) Stats {
+	stats := Stats{
+		Hits:         atomic.LoadUint64(&r.hits),
+		Misses:       atomic.LoadUint64(&r.misses),
+		Sets:         atomic.LoadUint64(&r.sets),
+		Deletes:      atomic.LoadUint64(&r.deletes),
+		LoaderCalls:  atomic.LoadUint64(&r.loaderCalls),
+		LoaderErrors: atomic.LoadUint64(&r.loaderErrors),
+		Latencies:    make(map[Operation]LatencyHistogram, len(operations)),
+	}
+
+	if counters != nil {
+		stats.Evictions = sinceBase(counters.Evictions(), atomic.LoadUint64(&r.evictionsBase))
+		stats.Expirations = sinceBase(counters.Expirations(), atomic.LoadUint64(&r.expirationsBase))
+	}
+
+	for i, op := range operations {
+		stats.Latencies[op] = r.latencies[i].snapshot()
+	}
+	return stats
+}
+
+// reset zeroes all counters. Counters are reset one by one, so operations
+// running concurrently may be partially counted.
+func (r *statsRecorder) reset(counters storeCounters) {
+	atomic.StoreUint64(&r.hits, 0)
+	atomic.StoreUint64(&r.misses, 0)
+	atomic.StoreUint64(&r.sets, 0)
+	atomic.StoreUint64(&r.deletes, 0)
+	atomic.StoreUint64(&r.loaderCalls, 0)
+	atomic.StoreUint64(&r.loaderErrors, 0)
+
+	if counters != nil {
+		atomic.StoreUint64(&r.evictionsBase, counters.Evictions())
+		atomic.StoreUint64(&r.expirationsBase, counters.Expirations())
+	}
+
+	for i := range r.latencies {
+		r.latencies[i].reset()
+	}
+}
+
+// sinceBase returns value minus base, treating a counter that went backwards
+// (such as a restarted Redis server) as restarted from zero
+func sinceBase(value, base uint64) uint64 {
+	if value < base {
+		return value
+	}
+	return value - base
+}
+
+// Stats returns a snapshot of the cache statistics. Namespaced views share
+// the statistics of the cache they were created from.
+func (c *Cache) Stats() Stats {
+	return c.stats.snapshot(c.storeCounters())
+}
+
+// ResetStats zeroes all statistics
+func (c *Cache) ResetStats() {
+	c.stats.reset(c.storeCounters())
+}
+
+// storeCounters finds the store that counts evictions and expirations
+func (c *Cache) storeCounters() storeCounters {
+	counters, _ := findStore[storeCounters](c.store)
+	return counters
+}
@@ -0,0 +1,98 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(&cache.Config{
+		Backend:         cache.BackendMemory,
+		DefaultTTL:      time.Minute,
+		CleanupInterval: 10 * time.Millisecond,
+		MaxEntries:      2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	c.Set(ctx, "a", "1")
+	c.Get(ctx, "a")
+	c.Get(ctx, "missing")
+	c.Delete(ctx, "a")
+
+	c.GetOrSet(ctx, "loaded", func() (interface{}, error) {
+		return "value", nil
+	}, time.Minute)
+	c.GetOrSet(ctx, "failed", func() (interface{}, error) {
+		return nil, errors.New("boom")
+	}, time.Minute)
+
+	// Namespaced views share the statistics
+	c.Namespace("ns").Get(ctx, "loaded")
+
+	// Overflow MaxEntries and let a short-lived entry expire
+	c.SetWithTTL(ctx, "b", "2", time.Minute)
+	c.SetWithTTL(ctx, "c", "3", 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	stats := c.Stats()
+
+	expected := map[string][2]uint64{
+		"hits":          {stats.Hits, 1},
+		"misses":        {stats.Misses, 4},
+		"sets":          {stats.Sets, 4},
+		"deletes":       {stats.Deletes, 1},
+		"loader calls":  {stats.LoaderCalls, 2},
+		"loader errors": {stats.LoaderErrors, 1},
+		"evictions":     {stats.Evictions, 1},
+		"expirations":   {stats.Expirations, 1},
+	}
+	for name, values := range expected {
+		if values[0] != values[1] {
+			t.Errorf("Expected %d %s, got %d", values[1], name, values[0])
+		}
+	}
+
+	if ratio := stats.HitRatio(); ratio != 0.2 {
+		t.Errorf("Expected hit ratio 0.2, got %f", ratio)
+	}
+
+	get := stats.Latencies[cache.OpGet]
+	if get.Count != 5 || len(get.Counts) != len(get.Buckets)+1 {
+		t.Errorf("Expected 5 gets in %d buckets, got %+v", len(get.Buckets)+1, get)
+	}
+	var total uint64
+	for _, n := range get.Counts {
+		total += n
+	}
+	if total != get.Count || get.Mean() <= 0 {
+		t.Errorf("Expected bucket counts to add up to %d, got %d", get.Count, total)
+	}
+	if stats.Latencies[cache.OpLoad].Count != 2 {
+		t.Errorf("Expected 2 loads, got %d", stats.Latencies[cache.OpLoad].Count)
+	}
+
+	c.Increment(ctx, "counter", 2)
+	c.Decrement(ctx, "counter", 1)
+	stats = c.Stats()
+	if stats.Latencies[cache.OpIncrement].Count != 1 || stats.Latencies[cache.OpDecrement].Count != 1 {
+		t.Errorf("Expected 1 increment and 1 decrement, got %d and %d",
+			stats.Latencies[cache.OpIncrement].Count, stats.Latencies[cache.OpDecrement].Count)
+	}
+
+	// Reset zeroes everything, including store counters
+	c.ResetStats()
+	stats = c.Stats()
+	if stats.Hits != 0 || stats.Sets != 0 || stats.Evictions != 0 || stats.Expirations != 0 ||
+		stats.Latencies[cache.OpGet].Count != 0 {
+		t.Errorf("Expected zeroed stats, got %+v", stats)
+	}
+}
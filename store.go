@@ -31,3 +31,71 @@ type Store interface {
 	// Close closes the connection
 	Close() error
 }
+
+// TagStore is implemented by stores that can group keys under tags and
+// remove every key carrying a tag at once
+type TagStore interface {
+	// SetWithTags stores a value and associates the key with the given tags
+	SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error
+
+	// InvalidateTags removes all keys associated with any of the tags and
+	// returns the removed keys
+	InvalidateTags(ctx context.Context, tags ...string) ([]string, error)
+}
+
+// TTLGetter is implemented by stores that can read a value together with
+// its remaining time to live
+type TTLGetter interface {
+	// GetWithTTL retrieves a value and its remaining TTL, 0 if it does not expire
+	GetWithTTL(ctx context.Context, key string) (interface{}, time.Duration, error)
+}
+
+// PrefixDeleter is implemented by stores that can remove every key starting
+// with a prefix without touching the rest of the store
+type PrefixDeleter interface {
+	// DeletePrefix removes all keys starting with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// LockStore is implemented by stores that can hold leased locks. Owners
+// identify the holder; fencing tokens increase with every acquisition of a key.
+type LockStore interface {
+	// AcquireLock takes the lock on key for ttl if it is free, returning the
+	// fencing token, or false if another owner holds it
+	AcquireLock(ctx context.Context, key, owner string, ttl time.Duration) (int64, bool, error)
+
+	// ExtendLock resets the lease to ttl, reporting false if owner no longer holds the lock
+	ExtendLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+
+	// ReleaseLock releases the lock, reporting false if owner no longer held it
+	ReleaseLock(ctx context.Context, key, owner string) (bool, error)
+}
+
+// leaseStore is implemented by stores that can take leases without fencing
+// tokens. GetOrSet uses them as stampede locks, which are taken for every
+// missed key and must not leave anything behind once released or expired.
+type leaseStore interface {
+	// acquireLease takes the lease on key for ttl if it is free
+	acquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+
+	// releaseLease releases the lease if owner still holds it
+	releaseLease(ctx context.Context, key, owner string) error
+}
+
+// findStore returns the first store of type T in a chain of store wrappers
+func findStore[T any](store Store) (T, bool) {
+	for store != nil {
+		if found, ok := store.(T); ok {
+			return found, true
+		}
+
+		wrapper, ok := store.(interface{ Unwrap() Store })
+		if !ok {
+			break
+		}
+		store = wrapper.Unwrap()
+	}
+
+	var zero T
+	return zero, false
+}
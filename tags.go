@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTagsNotSupported is returned when the store does not implement TagStore
+var ErrTagsNotSupported = errors.New("store does not support tags")
+
+// SetWithTags stores a value with a custom TTL and associates it with tags,
+// so it can later be removed together with other keys via InvalidateTags
+func (c *Cache) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
+	tagged, ok := c.store.(TagStore)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+
+	encoded, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+
+	defer c.stats.observe(OpSet, time.Now())
+	if err := tagged.SetWithTags(ctx, key, encoded, ttl, tags); err != nil {
+		c.events.failed(ctx, OpSet, c.prefix+key, err)
+		return err
+	}
+
+	atomic.AddUint64(&c.stats.sets, 1)
+	c.events.set(ctx, c.prefix+key, value, ttl)
+
+	fullTags := make([]string, len(tags))
+	for i, tag := range tags {
+		fullTags[i] = c.prefix + tag
+	}
+	c.refresh.written(c.prefix+key, refreshWrite{ttl: ttl, tags: fullTags})
+	return nil
+}
+
+// InvalidateTags removes every key associated with any of the given tags
+func (c *Cache) InvalidateTags(ctx context.Context, tags ...string) error {
+	tagged, ok := c.store.(TagStore)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+
+	removed, err := tagged.InvalidateTags(ctx, tags...)
+	for _, key := range removed {
+		c.refresh.forget(c.prefix + key)
+	}
+	return err
+}
@@ -0,0 +1,201 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+// testTags exercises tag invalidation against any cache
+func testTags(t *testing.T, c *cache.Cache) {
+	ctx := context.Background()
+
+	c.SetWithTags(ctx, "user:42:profile", "profile", time.Minute, "user:42")
+	c.SetWithTags(ctx, "user:42:orders", "orders", time.Minute, "user:42", "orders")
+	c.SetWithTags(ctx, "user:7:orders", "orders", time.Minute, "user:7", "orders")
+	c.SetWithTags(ctx, "user:42:avatar", "avatar", time.Minute, "user:42")
+	c.Set(ctx, "untagged", "value")
+
+	// Overwriting a key replaces its tags
+	c.SetWithTags(ctx, "user:42:avatar", "avatar", time.Minute, "avatars")
+
+	if err := c.InvalidateTags(ctx, "user:42"); err != nil {
+		t.Fatalf("InvalidateTags failed: %v", err)
+	}
+
+	for _, key := range []string{"user:42:profile", "user:42:orders"} {
+		if c.Has(ctx, key) {
+			t.Errorf("Expected %s to be invalidated", key)
+		}
+	}
+	for _, key := range []string{"user:7:orders", "user:42:avatar", "untagged"} {
+		if !c.Has(ctx, key) {
+			t.Errorf("Expected %s to survive", key)
+		}
+	}
+
+	// A deleted key is removed from its tags and may be reused untagged
+	c.Delete(ctx, "user:7:orders")
+	c.Set(ctx, "user:7:orders", "fresh")
+
+	if err := c.InvalidateTags(ctx, "orders", "unknown"); err != nil {
+		t.Fatalf("InvalidateTags failed: %v", err)
+	}
+	if !c.Has(ctx, "user:7:orders") {
+		t.Error("Expected re-created key to survive")
+	}
+
+	value, err := c.Get(ctx, "user:42:avatar")
+	if err != nil || value != "avatar" {
+		t.Errorf("Expected avatar, got %v (%v)", value, err)
+	}
+}
+
+func TestTags(t *testing.T) {
+	configs := map[string]*cache.Config{
+		"Memory":  cache.DefaultConfig(),
+		"Sharded": cache.DefaultConfig().WithShards(4),
+		"Bounded": cache.DefaultConfig().WithMaxEntries(100),
+		"Wrapped": cache.DefaultConfig().
+			WithCompression(cache.CompressionGzip, 1).
+			WithEncryption(map[string][]byte{"k": make([]byte, 32)}, "k"),
+	}
+
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			c, err := cache.New(config)
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+			defer c.Close()
+
+			testTags(t, c)
+		})
+	}
+}
+
+func TestTagsRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL not set")
+	}
+
+	for _, backend := range []cache.Backend{cache.BackendRedis, cache.BackendTiered} {
+		t.Run(string(backend), func(t *testing.T) {
+			c, err := cache.New(&cache.Config{
+				Backend:    backend,
+				RedisURL:   redisURL,
+				DefaultTTL: time.Minute,
+			})
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+			defer c.Close()
+
+			testTags(t, c)
+		})
+	}
+}
+
+func TestTagsRedisCluster(t *testing.T) {
+	addrs := os.Getenv("REDIS_CLUSTER_ADDRS")
+	if addrs == "" {
+		t.Skip("REDIS_CLUSTER_ADDRS not set")
+	}
+
+	c, err := cache.New(cache.DefaultConfig().
+		WithBackend(cache.BackendRedis).
+		WithRedisCluster(strings.Split(addrs, ",")...))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	testTags(t, c.Namespace("clustertest"))
+
+	// Prefix deletion scans every master
+	ns := c.Namespace("clusterprefix")
+	for i := 0; i < 50; i++ {
+		ns.Set(context.Background(), strconv.Itoa(i), "value")
+	}
+	if err := ns.Clear(context.Background()); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if ns.Has(context.Background(), strconv.Itoa(i)) {
+			t.Fatalf("Expected key %d to be cleared", i)
+		}
+	}
+}
+
+func TestTagIndexRedis(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL not set")
+	}
+
+	ctx := context.Background()
+
+	store, err := cache.NewRedisStore(redisURL)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	client := store.UniversalClient()
+
+	// Keys without tags have no index to clean up
+	store.Set(ctx, "tagindex:plain", "value", time.Minute)
+	if err := store.Delete(ctx, "tagindex:plain"); err != nil || store.Has(ctx, "tagindex:plain") {
+		t.Fatalf("Expected untagged key to be deleted, got %v", err)
+	}
+
+	// A key without expiry keeps its tag set alive, expired members are pruned
+	store.SetWithTags(ctx, "tagindex:forever", "value", 0, []string{"tagindex"})
+	for i := 0; i < 5; i++ {
+		store.SetWithTags(ctx, "tagindex:short:"+strconv.Itoa(i), "value", 10*time.Millisecond, []string{"tagindex"})
+	}
+	time.Sleep(50 * time.Millisecond)
+	store.SetWithTags(ctx, "tagindex:other", "value", time.Minute, []string{"tagindex"})
+	defer store.InvalidateTags(ctx, "tagindex")
+
+	if n := client.SCard(ctx, "__tag:tagindex").Val(); n != 2 {
+		t.Errorf("Expected expired members to be pruned, got %d members", n)
+	}
+}
+
+func TestTagIndexExpiry(t *testing.T) {
+	ctx := context.Background()
+
+	store := cache.NewMemoryStore(10 * time.Millisecond)
+	defer store.Close()
+
+	store.SetWithTags(ctx, "short", "value", 20*time.Millisecond, []string{"group"})
+	time.Sleep(50 * time.Millisecond)
+
+	// The expired key was reclaimed, so the tag no longer refers to it
+	removed, err := store.InvalidateTags(ctx, "group")
+	if err != nil {
+		t.Fatalf("InvalidateTags failed: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("Expected no keys removed, got %v", removed)
+	}
+
+	// Wrappers report stores without tag support
+	wrapped, err := cache.NewCompressedStore(untaggedStore{store}, cache.CompressionGzip, 0)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if _, err := wrapped.InvalidateTags(ctx, "group"); !errors.Is(err, cache.ErrTagsNotSupported) {
+		t.Errorf("Expected ErrTagsNotSupported, got %v", err)
+	}
+}
+
+// untaggedStore hides the tag support of the store it wraps
+type untaggedStore struct{ cache.Store }
@@ -0,0 +1,299 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// defaultL1TTL is how long values stay in the local tier when L1TTL is unset
+	defaultL1TTL = 1 * time.Minute
+
+	// defaultL1MaxEntries bounds the local tier when no memory limits are configured
+	defaultL1MaxEntries = 10000
+)
+
+// TieredStore serves reads from a fast local tier (L1) and falls back to a
+// shared tier (L2), typically a bounded MemoryStore in front of a RedisStore.
+// Writes and deletes go through both tiers; counters are always authoritative in L2.
+type TieredStore struct {
+	l1    Store
+	l2    Store
+	l1TTL time.Duration
+	bus   *InvalidationBus
+}
+
+// NewTieredStore creates a two-tier store. Values are kept in l1 for at most l1TTL.
+func NewTieredStore(l1, l2 Store, l1TTL time.Duration) *TieredStore {
+	return &TieredStore{
+		l1:    l1,
+		l2:    l2,
+		l1TTL: l1TTL,
+	}
+}
+
+// WithInvalidation publishes every write on bus so other instances drop their
+// local copies. The bus is closed together with the store.
+func (t *TieredStore) WithInvalidation(bus *InvalidationBus) *TieredStore {
+	t.bus = bus
+	return t
+}
+
+// invalidate notifies other instances that keys changed in L2.
+// Publishing is best-effort: subscribers flush their local tier whenever
+// their subscription drops, which covers most outages.
+func (t *TieredStore) invalidate(ctx context.Context, keys ...string) {
+	if t.bus != nil {
+		t.bus.Invalidate(ctx, keys...)
+	}
+}
+
+// localTTL returns the TTL used for the local copy of a value
+func (t *TieredStore) localTTL(ttl time.Duration) time.Duration {
+	if t.l1TTL <= 0 || (ttl > 0 && ttl < t.l1TTL) {
+		return ttl
+	}
+	return t.l1TTL
+}
+
+// Get retrieves a value from L1, falling back to L2 and populating L1 on a hit.
+// The local copy never outlives the entry in L2 if L2 implements TTLGetter.
+func (t *TieredStore) Get(ctx context.Context, key string) (interface{}, error) {
+	if value, err := t.l1.Get(ctx, key); err == nil {
+		return value, nil
+	}
+
+	var value interface{}
+	var remaining time.Duration
+	var err error
+	if ttlGetter, ok := t.l2.(TTLGetter); ok {
+		value, remaining, err = ttlGetter.GetWithTTL(ctx, key)
+	} else {
+		value, err = t.l2.Get(ctx, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The local copy is best-effort - L2 already answered
+	t.l1.Set(ctx, key, value, t.localTTL(remaining))
+
+	return value, nil
+}
+
+// Set stores a value in L2, then in L1
+func (t *TieredStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := t.l2.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	if err := t.l1.Set(ctx, key, value, t.localTTL(ttl)); err != nil {
+		// Don't leave a stale local copy behind
+		t.l1.Delete(ctx, key)
+	}
+	t.invalidate(ctx, key)
+	return nil
+}
+
+// SetWithTags stores a tagged value in L2, then in L1.
+// Tags are tracked by L2 only; invalidating a tag drops the removed keys from L1.
+func (t *TieredStore) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags []string) error {
+	tagged, ok := t.l2.(TagStore)
+	if !ok {
+		return ErrTagsNotSupported
+	}
+
+	if err := tagged.SetWithTags(ctx, key, value, ttl, tags); err != nil {
+		return err
+	}
+
+	if err := t.l1.Set(ctx, key, value, t.localTTL(ttl)); err != nil {
+		t.l1.Delete(ctx, key)
+	}
+	t.invalidate(ctx, key)
+	return nil
+}
+
+// InvalidateTags removes all keys associated with any of the tags from both tiers
+func (t *TieredStore) InvalidateTags(ctx context.Context, tags ...string) ([]string, error) {
+	tagged, ok := t.l2.(TagStore)
+	if !ok {
+		return nil, ErrTagsNotSupported
+	}
+
+	removed, err := tagged.InvalidateTags(ctx, tags...)
+	for _, key := range removed {
+		t.l1.Delete(ctx, key)
+	}
+	t.invalidate(ctx, removed...)
+	return removed, err
+}
+
+// Delete removes a value from both tiers
+func (t *TieredStore) Delete(ctx context.Context, key string) error {
+	err := t.l2.Delete(ctx, key)
+	t.l1.Delete(ctx, key)
+	t.invalidate(ctx, key)
+	return err
+}
+
+// DeletePrefix removes all keys starting with prefix from both tiers
+func (t *TieredStore) DeletePrefix(ctx context.Context, prefix string) error {
+	deleter, ok := t.l2.(PrefixDeleter)
+	if !ok {
+		return ErrPrefixNotSupported
+	}
+
+	if err := deleter.DeletePrefix(ctx, prefix); err != nil {
+		return err
+	}
+
+	if local, ok := t.l1.(PrefixDeleter); ok {
+		local.DeletePrefix(ctx, prefix)
+	} else {
+		t.l1.Clear(ctx)
+	}
+	if t.bus != nil {
+		t.bus.InvalidatePrefix(ctx, prefix)
+	}
+	return nil
+}
+
+// Has checks if a key exists in either tier
+func (t *TieredStore) Has(ctx context.Context, key string) bool {
+	return t.l1.Has(ctx, key) || t.l2.Has(ctx, key)
+}
+
+// Increment increments a numeric value in L2 and drops the local copy
+func (t *TieredStore) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	value, err := t.l2.Increment(ctx, key, delta)
+	t.l1.Delete(ctx, key)
+	t.invalidate(ctx, key)
+	return value, err
+}
+
+// Decrement decrements a numeric value in L2 and drops the local copy
+func (t *TieredStore) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	value, err := t.l2.Decrement(ctx, key, delta)
+	t.l1.Delete(ctx, key)
+	t.invalidate(ctx, key)
+	return value, err
+}
+
+// Clear removes all entries from both tiers
+func (t *TieredStore) Clear(ctx context.Context) error {
+	if err := t.l2.Clear(ctx); err != nil {
+		return err
+	}
+
+	t.l1.Clear(ctx)
+	if t.bus != nil {
+		t.bus.InvalidateAll(ctx)
+	}
+	return nil
+}
+
+// Close closes both tiers
+func (t *TieredStore) Close() error {
+	if t.bus != nil {
+		t.bus.Close()
+	}
+	err := t.l2.Close()
+	t.l1.Close()
+	return err
+}
+
+// Evictions returns the evictions reported by both tiers
+func (t *TieredStore) Evictions() uint64 {
+	var total uint64
+	for _, tier := range []Store{t.l1, t.l2} {
+		if counters, ok := tier.(storeCounters); ok {
+			total += counters.Evictions()
+		}
+	}
+	return total
+}
+
+// Expirations returns the expirations reported by both tiers
+func (t *TieredStore) Expirations() uint64 {
+	var total uint64
+	for _, tier := range []Store{t.l1, t.l2} {
+		if counters, ok := tier.(storeCounters); ok {
+			total += counters.Expirations()
+		}
+	}
+	return total
+}
+
+// L1 returns the local tier
+func (t *TieredStore) L1() Store {
+	return t.l1
+}
+
+// L2 returns the shared tier
+func (t *TieredStore) L2() Store {
+	return t.l2
+}
+
+// lockStore returns the shared tier as a LockStore; locks are never taken in L1
+func (t *TieredStore) lockStore() (LockStore, error) {
+	locks, ok := findStore[LockStore](t.l2)
+	if !ok {
+		return nil, ErrLocksNotSupported
+	}
+	return locks, nil
+}
+
+// AcquireLock takes the lock on key in L2
+func (t *TieredStore) AcquireLock(ctx context.Context, key, owner string, ttl time.Duration) (int64, bool, error) {
+	locks, err := t.lockStore()
+	if err != nil {
+		return 0, false, err
+	}
+	return locks.AcquireLock(ctx, key, owner, ttl)
+}
+
+// ExtendLock resets the lease of a lock in L2 still held by owner
+func (t *TieredStore) ExtendLock(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	locks, err := t.lockStore()
+	if err != nil {
+		return false, err
+	}
+	return locks.ExtendLock(ctx, key, owner, ttl)
+}
+
+// ReleaseLock releases a lock in L2 still held by owner
+func (t *TieredStore) ReleaseLock(ctx context.Context, key, owner string) (bool, error) {
+	locks, err := t.lockStore()
+	if err != nil {
+		return false, err
+	}
+	return locks.ReleaseLock(ctx, key, owner)
+}
+
+// acquireLease takes a stampede lease in L2
+func (t *TieredStore) acquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	leases, ok := findStore[leaseStore](t.l2)
+	if !ok {
+		return false, ErrLocksNotSupported
+	}
+	return leases.acquireLease(ctx, key, owner, ttl)
+}
+
+// releaseLease releases a stampede lease in L2
+func (t *TieredStore) releaseLease(ctx context.Context, key, owner string) error {
+	leases, ok := findStore[leaseStore](t.l2)
+	if !ok {
+		return ErrLocksNotSupported
+	}
+	return leases.releaseLease(ctx, key, owner)
+}
+
+// AllowN applies a rate limit in L2, so it is shared by every instance
+func (t *TieredStore) AllowN(ctx context.Context, key string, limit RateLimit, n int64) (RateLimitResult, error) {
+	limiter, ok := findStore[RateLimitStore](t.l2)
+	if !ok {
+		return RateLimitResult{}, ErrRateLimitNotSupported
+	}
+	return limiter.AllowN(ctx, key, limit, n)
+}
@@ -0,0 +1,110 @@
+package cache_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+func TestTieredStore(t *testing.T) {
+	ctx := context.Background()
+
+	l1 := cache.NewMemoryStoreWithOptions(cache.MemoryOptions{MaxEntries: 10})
+	l2 := cache.NewMemoryStore(time.Minute)
+	store := cache.NewTieredStore(l1, l2, time.Minute)
+	defer store.Close()
+
+	// Writes go through both tiers
+	store.Set(ctx, "key1", "value1", time.Hour)
+	if !l1.Has(ctx, "key1") || !l2.Has(ctx, "key1") {
+		t.Error("key1 should exist in both tiers")
+	}
+
+	// L2 hits populate L1
+	l2.Set(ctx, "key2", "value2", time.Hour)
+	value, err := store.Get(ctx, "key2")
+	if err != nil {
+		t.Errorf("Get failed: %v", err)
+	}
+	if value != "value2" {
+		t.Errorf("Expected value2, got %v", value)
+	}
+	if !l1.Has(ctx, "key2") {
+		t.Error("key2 should be copied to L1")
+	}
+
+	// Local copies expire with the L2 entry
+	l2.Set(ctx, "short", "value", 50*time.Millisecond)
+	store.Get(ctx, "short")
+	time.Sleep(80 * time.Millisecond)
+	if _, err := store.Get(ctx, "short"); err == nil {
+		t.Error("Expected local copy to expire with L2")
+	}
+
+	// Counters are authoritative in L2
+	store.Set(ctx, "counter", int64(5), time.Hour)
+	count, err := store.Increment(ctx, "counter", 1)
+	if err != nil {
+		t.Errorf("Increment failed: %v", err)
+	}
+	if count != 6 {
+		t.Errorf("Expected 6, got %d", count)
+	}
+	if l1.Has(ctx, "counter") {
+		t.Error("Local counter copy should be dropped")
+	}
+
+	// Deletes go through both tiers
+	store.Delete(ctx, "key1")
+	if l1.Has(ctx, "key1") || l2.Has(ctx, "key1") {
+		t.Error("key1 should be deleted from both tiers")
+	}
+}
+
+func TestTieredInvalidation(t *testing.T) {
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL == "" {
+		t.Skip("REDIS_URL not set")
+	}
+
+	ctx := context.Background()
+	config := &cache.Config{
+		Backend:             cache.BackendTiered,
+		RedisURL:            redisURL,
+		DefaultTTL:          time.Minute,
+		InvalidationChannel: "go-cache-test:invalidate",
+	}
+
+	a, err := cache.New(config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer a.Close()
+
+	b, err := cache.New(config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer b.Close()
+
+	// Give both subscriptions time to start
+	time.Sleep(100 * time.Millisecond)
+
+	a.Set(ctx, "invalidation_key", "old")
+	if value, _ := b.Get(ctx, "invalidation_key"); value != "old" {
+		t.Fatalf("Expected old, got %v", value)
+	}
+
+	// b now holds a local copy, which a's write must invalidate
+	a.Set(ctx, "invalidation_key", "new")
+	time.Sleep(100 * time.Millisecond)
+
+	if value, _ := b.Get(ctx, "invalidation_key"); value != "new" {
+		t.Errorf("Expected new, got %v", value)
+	}
+
+	a.Delete(ctx, "invalidation_key")
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// TypeMismatchError is returned when a cached value cannot be converted to the requested type
+type TypeMismatchError struct {
+	Key      string
+	Expected reflect.Type
+	Actual   reflect.Type
+	Err      error
+}
+
+// Error implements the error interface
+func (e *TypeMismatchError) Error() string {
+	msg := fmt.Sprintf("cache: value for key %q is %v, not %v", e.Key, e.Actual, e.Expected)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying decoding error, if any
+func (e *TypeMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// newTypeMismatch reports that a cached value could not be converted to T
+func newTypeMismatch[T any](key string, value interface{}, err error) *TypeMismatchError {
+	return &TypeMismatchError{
+		Key:      key,
+		Expected: reflect.TypeOf((*T)(nil)).Elem(),
+		Actual:   reflect.TypeOf(value),
+		Err:      err,
+	}
+}
+
+// TypedCache is a type-safe view over a Cache for values of type T
+type TypedCache[T any] struct {
+	cache *Cache
+}
+
+// NewTyped creates a TypedCache for values of type T backed by the given cache
+func NewTyped[T any](c *Cache) *TypedCache[T] {
+	return &TypedCache[T]{cache: c}
+}
+
+// Cache returns the underlying untyped cache
+func (t *TypedCache[T]) Cache() *Cache {
+	return t.cache
+}
+
+// Get retrieves a value and converts it to T
+func (t *TypedCache[T]) Get(ctx context.Context, key string) (T, error) {
+	value, err := t.cache.getRaw(ctx, key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return convertValue[T](t.cache, key, value)
+}
+
+// Set stores a value with the default TTL
+func (t *TypedCache[T]) Set(ctx context.Context, key string, value T) error {
+	return t.cache.Set(ctx, key, value)
+}
+
+// SetWithTTL stores a value with a custom TTL
+func (t *TypedCache[T]) SetWithTTL(ctx context.Context, key string, value T, ttl time.Duration) error {
+	return t.cache.SetWithTTL(ctx, key, value, ttl)
+}
+
+// GetOrSet retrieves a value or fetches and stores it if not found.
+// The fetcher is shared by concurrent callers, so its context carries the
+// values of the first caller's context but not its cancellation.
+func (t *TypedCache[T]) GetOrSet(ctx context.Context, key string, fetcher func(ctx context.Context) (T, error), ttl time.Duration) (T, error) {
+	value, err := t.cache.getOrSet(ctx, key, func() (interface{}, error) {
+		return fetcher(context.WithoutCancel(ctx))
+	}, ttl)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	if stored, ok := value.(storedValue); ok {
+		return convertValue[T](t.cache, key, stored.value)
+	}
+
+	// Fresh values come straight from a fetcher and are never decoded
+	if typed, ok := value.(T); ok {
+		return typed, nil
+	}
+	var zero T
+	return zero, newTypeMismatch[T](key, value, nil)
+}
+
+// Remember is an alias for GetOrSet with default TTL
+func (t *TypedCache[T]) Remember(ctx context.Context, key string, fetcher func(ctx context.Context) (T, error)) (T, error) {
+	return t.GetOrSet(ctx, key, fetcher, t.cache.defaultTTL)
+}
+
+// GetMany retrieves multiple values at once, skipping missing keys.
+// The first value that cannot be converted to T aborts the call.
+func (t *TypedCache[T]) GetMany(ctx context.Context, keys []string) (map[string]T, error) {
+	results := make(map[string]T, len(keys))
+
+	for _, key := range keys {
+		value, err := t.cache.getRaw(ctx, key)
+		if err != nil {
+			continue
+		}
+
+		typed, err := convertValue[T](t.cache, key, value)
+		if err != nil {
+			return nil, err
+		}
+		results[key] = typed
+	}
+
+	return results, nil
+}
+
+// SetMany stores multiple values at once
+func (t *TypedCache[T]) SetMany(ctx context.Context, items map[string]T, ttl time.Duration) error {
+	for key, value := range items {
+		if err := t.cache.SetWithTTL(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes a value from the cache
+func (t *TypedCache[T]) Delete(ctx context.Context, key string) error {
+	return t.cache.Delete(ctx, key)
+}
+
+// Has checks if a key exists
+func (t *TypedCache[T]) Has(ctx context.Context, key string) bool {
+	return t.cache.Has(ctx, key)
+}
+
+// convertValue converts a value returned by a store to T.
+// Codec-encoded values are decoded directly into T. Otherwise the memory
+// backend hands back the original value, while Redis returns the JSON
+// (or raw string) representation, so both are accepted.
+func convertValue[T any](c *Cache, key string, value interface{}) (T, error) {
+	var result T
+	if decoded, err := c.decodeEncoded(value, &result); decoded {
+		if err != nil {
+			return result, newTypeMismatch[T](key, value, err)
+		}
+		return result, nil
+	}
+	value = unescapeRaw(value)
+
+	if typed, ok := value.(T); ok {
+		return typed, nil
+	}
+
+	data, ok := asBytes(value)
+	if !ok {
+		return result, newTypeMismatch[T](key, value, nil)
+	}
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, newTypeMismatch[T](key, value, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,153 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/OkanUysal/go-cache"
+)
+
+func TestTypedCache(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(&cache.Config{
+		Backend: cache.BackendMemory,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	type User struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	users := cache.NewTyped[User](c)
+
+	// Test Set/Get
+	err = users.Set(ctx, "user:1", User{ID: 1, Name: "John"})
+	if err != nil {
+		t.Errorf("Set failed: %v", err)
+	}
+
+	user, err := users.Get(ctx, "user:1")
+	if err != nil {
+		t.Errorf("Get failed: %v", err)
+	}
+	if user.ID != 1 || user.Name != "John" {
+		t.Errorf("Expected John, got %+v", user)
+	}
+
+	// Test JSON string (as returned by Redis)
+	c.Set(ctx, "user:2", `{"id":2,"name":"Jane"}`)
+	user, err = users.Get(ctx, "user:2")
+	if err != nil {
+		t.Errorf("Get failed: %v", err)
+	}
+	if user.ID != 2 || user.Name != "Jane" {
+		t.Errorf("Expected Jane, got %+v", user)
+	}
+
+	// Test type mismatch
+	c.Set(ctx, "user:3", 42)
+	_, err = users.Get(ctx, "user:3")
+	var mismatch *cache.TypeMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("Expected TypeMismatchError, got %v", err)
+	}
+
+	// Test missing key
+	_, err = users.Get(ctx, "user:404")
+	if !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// Test GetMany
+	results, err := users.GetMany(ctx, []string{"user:1", "user:2", "user:404"})
+	if err != nil {
+		t.Errorf("GetMany failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results, got %d", len(results))
+	}
+}
+
+func TestTypedGetOrSet(t *testing.T) {
+	ctx := context.Background()
+
+	c, err := cache.New(&cache.Config{
+		Backend: cache.BackendMemory,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	counts := cache.NewTyped[int](c)
+
+	fetchCount := 0
+	fetcher := func(ctx context.Context) (int, error) {
+		fetchCount++
+		return 7, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		value, err := counts.GetOrSet(ctx, "answer", fetcher, 1*time.Hour)
+		if err != nil {
+			t.Errorf("GetOrSet failed: %v", err)
+		}
+		if value != 7 {
+			t.Errorf("Expected 7, got %d", value)
+		}
+	}
+
+	if fetchCount != 1 {
+		t.Errorf("Expected fetch count 1, got %d", fetchCount)
+	}
+}
+
+func TestTypedGetOrSetCancel(t *testing.T) {
+	c, err := cache.New(cache.DefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer c.Close()
+
+	counts := cache.NewTyped[int](c)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetcher := func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		return 7, nil
+	}
+
+	// The first caller gives up while the fetch it started is running
+	first, cancel := context.WithCancel(context.Background())
+	go counts.GetOrSet(first, "answer", fetcher, time.Hour)
+	<-started
+
+	result := make(chan error, 1)
+	go func() {
+		value, err := counts.GetOrSet(context.Background(), "answer", fetcher, time.Hour)
+		if err == nil && value != 7 {
+			err = errors.New("unexpected value")
+		}
+		result <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	close(release)
+
+	if err := <-result; err != nil {
+		t.Errorf("Expected the waiter to get the fetched value, got %v", err)
+	}
+}